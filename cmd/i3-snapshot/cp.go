@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/a9sk/i3-snapshot/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var cpCmd = &cobra.Command{
+	Use:     "cp [name] [new-name]",
+	Aliases: []string{"copy"},
+	Short:   "Copy a saved snapshot under a new name",
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		if err := store.Copy(st, args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("copied snapshot %s to %s\n", args[0], args[1])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cpCmd)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/a9sk/i3-snapshot/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Autosave the session whenever windows or workspaces change",
+	Long: `Run in the background (e.g. "exec --no-startup-id i3-snapshot daemon" in the i3 config)
+and save the session every time windows or workspaces change, plus a final save when i3
+exits or restarts. Previous autosaves are kept as generations of the autosave snapshot.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+		debounce, _ := cmd.Flags().GetDuration("debounce")
+
+		st, err := openStore()
+		if err != nil {
+			return err
+		}
+		marker, err := openMarker(name)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		opts := snapshot.DaemonOptions{
+			Name:     name,
+			Debounce: debounce,
+			Save: snapshot.SaveOptions{
+				Description: "autosave",
+				ToolVersion: version,
+			},
+			Marker: marker,
+			Logf: func(format string, args ...any) {
+				fmt.Printf("%s "+format, append([]any{time.Now().Format("15:04:05")}, args...)...)
+			},
+		}
+		return snapshot.Daemon(ctx, st, opts)
+	},
+}
+
+func init() {
+	daemonCmd.Flags().String("name", "autosave", "snapshot to autosave to")
+	daemonCmd.Flags().Duration("debounce", 5*time.Second, "quiet period after a change before saving")
+	daemonCmd.Flags().IntVar(&retention.KeepLast, "keep-last", retention.KeepLast, "number of previous autosaves to keep")
+	daemonCmd.Flags().IntVar(&retention.KeepDaily, "keep-daily", retention.KeepDaily, "also keep the newest autosave of each of the last N days")
+	rootCmd.AddCommand(daemonCmd)
+}
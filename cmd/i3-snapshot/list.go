@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"github.com/a9sk/i3-snapshot/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List saved snapshots",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		names, err := st.List()
+		if err != nil {
+			return err
+		}
+
+		all, _ := cmd.Flags().GetBool("all")
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCREATED\tWORKSPACES\tWINDOWS\tDESCRIPTION")
+		for _, name := range names {
+			snap, err := snapshot.Load(st, name)
+			if err != nil {
+				// keep listing the others, a broken file should not hide them
+				fmt.Fprintf(w, "%s\t(unreadable: %v)\t\t\t\n", name, err)
+				continue
+			}
+
+			created, workspaces, windows, description := "-", len(snap.Workspaces), countWindows(snap), ""
+			if snap.Metadata != nil {
+				created = snap.Metadata.CreatedAt.Local().Format("2006-01-02 15:04")
+				description = snap.Metadata.Description
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", name, created, workspaces, windows, description)
+
+			if !all {
+				continue
+			}
+			gens, err := st.Generations(name)
+			if err != nil {
+				return err
+			}
+			for i, g := range gens {
+				fmt.Fprintf(w, "  %s (%s~%d)\t%s\t\t\t\n", g.Ref, name, i+1, g.Time.Format("2006-01-02 15:04"))
+			}
+		}
+		return w.Flush()
+	},
+}
+
+// countWindows returns the number of windows recorded in a snapshot, scratchpad included.
+func countWindows(snap models.Snapshot) int {
+	n := 0
+	for _, ws := range snap.Workspaces {
+		n += len(ws.Windows)
+	}
+	if snap.Scratchpad != nil {
+		n += len(snap.Scratchpad.Windows)
+	}
+	return n
+}
+
+func init() {
+	listCmd.Flags().BoolP("all", "a", false, "also list older generations of each snapshot")
+	rootCmd.AddCommand(listCmd)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/a9sk/i3-snapshot/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var mvCmd = &cobra.Command{
+	Use:     "mv [name] [new-name]",
+	Aliases: []string{"rename"},
+	Short:   "Rename a saved snapshot",
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		if err := store.Rename(st, args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("renamed snapshot %s to %s\n", args[0], args[1])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mvCmd)
+}
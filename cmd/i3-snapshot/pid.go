@@ -18,7 +18,7 @@ var pidCmd = &cobra.Command{
 			return fmt.Errorf("invalid pid %q: %w", args[0], err)
 		}
 
-		command, err := proc.GetCommandFromPID(pid)
+		command, err := proc.NewFS(proc.DefaultRoot).GetCommandFromPID(pid)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"github.com/a9sk/i3-snapshot/internal/snapshot"
+	"github.com/a9sk/i3-snapshot/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var recoverCmd = &cobra.Command{
+	Use:   "recover",
+	Short: "Restore the last autosave if the previous session crashed",
+	Long: `Restore the last autosave written by "i3-snapshot daemon" if the previous session did
+not end cleanly, i.e. i3 never announced that it was exiting. Meant to be run from the i3
+config before the daemon starts, e.g.
+
+    exec --no-startup-id "i3-snapshot recover --prompt 'rofi -dmenu'; i3-snapshot daemon"
+
+With --prompt, the given dmenu-style command is shown a choice between restoring and
+skipping on stdin, and the autosave is only restored if the restore line is picked.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+		prompt, _ := cmd.Flags().GetString("prompt")
+
+		marker, err := openMarker(name)
+		if err != nil {
+			return err
+		}
+		clean, err := marker.IsClean()
+		if err != nil {
+			return err
+		}
+		if clean {
+			fmt.Println("previous session ended cleanly, nothing to recover")
+			return nil
+		}
+
+		st, err := openStore()
+		if err != nil {
+			return err
+		}
+		snap, err := snapshot.Load(st, name)
+		if errors.Is(err, store.ErrNotFound) {
+			fmt.Printf("no autosave %q found, nothing to recover\n", name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if prompt != "" {
+			ok, err := askRecover(prompt, snap)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("recovery skipped")
+				// do not ask again until the next session ends
+				return marker.MarkClean()
+			}
+		}
+
+		fmt.Printf("previous session did not end cleanly, restoring %s\n", name)
+		if err := snapshot.Restore(st, name, snapshot.RestoreOptions{}); err != nil {
+			return err
+		}
+		// the autosave has been dealt with; running recover again must not restore it twice
+		return marker.MarkClean()
+	},
+}
+
+func init() {
+	recoverCmd.Flags().String("name", "autosave", "autosave snapshot to recover")
+	recoverCmd.Flags().String("prompt", "", "dmenu-style command to confirm the restore with, e.g. 'rofi -dmenu'")
+	rootCmd.AddCommand(recoverCmd)
+}
+
+// askRecover runs the prompt command through sh with a restore and a skip line on stdin
+// and reports whether the restore line was picked.
+func askRecover(prompt string, snap models.Snapshot) (bool, error) {
+	restore := "Restore previous session"
+	if m := snap.Metadata; m != nil {
+		restore = fmt.Sprintf("Restore previous session (%s, %d windows)",
+			m.CreatedAt.Local().Format("2006-01-02 15:04"), m.WindowCount)
+	}
+	choices := restore + "\nSkip\n"
+
+	var out bytes.Buffer
+	c := exec.Command("sh", "-c", prompt)
+	c.Stdin = strings.NewReader(choices)
+	c.Stdout = &out
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		// dmenu and rofi exit non-zero when the prompt is dismissed
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return false, nil
+		}
+		return false, fmt.Errorf("running prompt: %w", err)
+	}
+	return strings.TrimSpace(out.String()) == restore, nil
+}
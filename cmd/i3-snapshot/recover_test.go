@@ -0,0 +1,117 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/a9sk/i3-snapshot/internal/i3/i3test"
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"go.i3wm.org/i3"
+)
+
+// useStoreDir points the commands at an empty snapshot directory for the test.
+func useStoreDir(t *testing.T) {
+	saved := storeDir
+	storeDir = t.TempDir()
+	t.Cleanup(func() { storeDir = saved })
+}
+
+// putAutosave saves a snapshot with a single empty workspace as the autosave.
+func putAutosave(t *testing.T) {
+	t.Helper()
+	st, err := openStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := models.Snapshot{
+		SchemaVersion: models.SchemaVersion,
+		Name:          "autosave",
+		Workspaces:    []models.WorkspaceSnapshot{{Name: "3", Root: models.LayoutNode{Type: "workspace"}}},
+	}
+	if err := st.Put("autosave", snap); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// markSession sets the autosave's marker to clean or clears it.
+func markSession(t *testing.T, clean bool) {
+	t.Helper()
+	m, err := openMarker("autosave")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clean {
+		err = m.MarkClean()
+	} else {
+		err = m.Clear()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sessionClean reports whether the autosave's marker is set.
+func sessionClean(t *testing.T) bool {
+	t.Helper()
+	m, err := openMarker("autosave")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clean, err := m.IsClean()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clean
+}
+
+// noI3 makes any attempt to talk to i3 fail.
+func noI3(t *testing.T) {
+	saved := i3.SocketPathHook
+	i3.SocketPathHook = func() (string, error) { return "/nonexistent/i3-ipc.sock", nil }
+	t.Cleanup(func() { i3.SocketPathHook = saved })
+}
+
+func TestRecoverCleanSession(t *testing.T) {
+	useStoreDir(t)
+	noI3(t)
+	putAutosave(t)
+	markSession(t, true)
+
+	// a restore would fail without i3
+	if err := recoverCmd.RunE(recoverCmd, nil); err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+	if !sessionClean(t) {
+		t.Error("marker cleared by recover")
+	}
+}
+
+func TestRecoverWithoutAutosave(t *testing.T) {
+	useStoreDir(t)
+	noI3(t)
+	markSession(t, false)
+
+	if err := recoverCmd.RunE(recoverCmd, nil); err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+	if sessionClean(t) {
+		t.Error("marker set although nothing was recovered")
+	}
+}
+
+func TestRecoverCrashedSession(t *testing.T) {
+	useStoreDir(t)
+	srv := i3test.Start(t, "DP-1")
+	putAutosave(t)
+	markSession(t, false)
+
+	if err := recoverCmd.RunE(recoverCmd, nil); err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+	if !slices.Contains(srv.Commands(), "workspace 3") {
+		t.Errorf("autosave not restored, i3 got %q", srv.Commands())
+	}
+	if !sessionClean(t) {
+		t.Error("marker not set after recovering, the next recover would restore again")
+	}
+}
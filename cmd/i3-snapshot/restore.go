@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/a9sk/i3-snapshot/internal/snapshot"
 	"github.com/spf13/cobra"
@@ -10,17 +12,101 @@ import (
 var restoreCmd = &cobra.Command{
 	Use:   "restore [name]",
 	Short: "Restore a previously saved workspace layout",
-	Args:  cobra.ExactArgs(1),
+	Long: `Restore a previously saved workspace layout.
+
+Older generations of a snapshot can be restored with name@timestamp (any prefix of
+the timestamp, e.g. work@2026-10-17T09:30) or name~N (N generations back, e.g. work~2).
+
+Use --workspace to restore only some of the saved workspaces, and --as or --offset to
+restore them under other names, e.g. --as 3=7 or --offset 4 to restore 3 as 7.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
-		fmt.Printf("restoring snapshot: %s\n", name)
+		rewrite, _ := cmd.Flags().GetBool("rewrite")
+		mappings, _ := cmd.Flags().GetStringArray("map-output")
+		workspaces, _ := cmd.Flags().GetStringArray("workspace")
+		renames, _ := cmd.Flags().GetStringArray("as")
+		offset, _ := cmd.Flags().GetInt("offset")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+
+		if dryRun {
+			fmt.Printf("restore plan for snapshot: %s\n", name)
+		} else {
+			fmt.Printf("restoring snapshot: %s\n", name)
+		}
 
-		if err := snapshot.Restore(name); err != nil {
+		outputMap, err := parseOutputMap(mappings)
+		if err != nil {
+			fmt.Printf("error restoring snapshot: %v\n", err)
+			return
+		}
+		rename, err := parseRenames(renames)
+		if err != nil {
+			fmt.Printf("error restoring snapshot: %v\n", err)
+			return
+		}
+
+		opts := snapshot.RestoreOptions{
+			Rewrite:    rewrite,
+			OutputMap:  outputMap,
+			Workspaces: workspaces,
+			Rename:     rename,
+			Offset:     offset,
+			DryRun:     dryRun,
+			Out:        os.Stdout,
+		}
+		if verbose {
+			opts.Progress = func(done, total int, step snapshot.Step) {
+				fmt.Printf("[%d/%d] %s\n", done+1, total, step.Describe())
+			}
+		}
+
+		st, err := openStore()
+		if err != nil {
+			fmt.Printf("error restoring snapshot: %v\n", err)
+			return
+		}
+
+		if err := snapshot.Restore(st, name, opts); err != nil {
 			fmt.Printf("error restoring snapshot: %v\n", err)
 		}
 	},
 }
 
 func init() {
+	restoreCmd.Flags().Bool("rewrite", false, "save the snapshot back in the current format if it had to be migrated")
+	restoreCmd.Flags().StringArray("map-output", nil, "restore workspaces of a saved output on another output, e.g. DP-1=eDP-1 (repeatable)")
+	restoreCmd.Flags().StringArray("workspace", nil, "only restore the saved workspace with this name (repeatable)")
+	restoreCmd.Flags().StringArray("as", nil, "restore a saved workspace under another name, e.g. 3=7 (repeatable)")
+	restoreCmd.Flags().Int("offset", 0, "add N to the number of every numbered workspace not renamed with --as")
+	restoreCmd.Flags().Bool("dry-run", false, "print every i3 command and launch the restore would perform, without performing them")
+	restoreCmd.Flags().BoolP("verbose", "v", false, "print every step of the restore as it runs")
 	rootCmd.AddCommand(restoreCmd)
 }
+
+// parseOutputMap turns SAVED=TARGET pairs into a map of saved output name to target output name.
+func parseOutputMap(pairs []string) (map[string]string, error) {
+	out := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		from, to, ok := strings.Cut(p, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid output mapping %q, expected SAVED=TARGET", p)
+		}
+		out[from] = to
+	}
+	return out, nil
+}
+
+// parseRenames turns SAVED=NEW pairs into a map of saved workspace name to new name.
+func parseRenames(pairs []string) (map[string]string, error) {
+	out := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		from, to, ok := strings.Cut(p, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid workspace rename %q, expected SAVED=NEW", p)
+		}
+		out[from] = to
+	}
+	return out, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var rmCmd = &cobra.Command{
+	Use:     "rm [name]...",
+	Aliases: []string{"delete"},
+	Short:   "Delete saved snapshots",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		for _, name := range args {
+			if err := st.Delete(name); err != nil {
+				return err
+			}
+			fmt.Printf("deleted snapshot: %s\n", name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rmCmd)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/a9sk/i3-snapshot/internal/i3"
+	"github.com/a9sk/i3-snapshot/internal/store"
 	"github.com/spf13/cobra"
 )
 
@@ -26,3 +27,42 @@ func Execute() {
 	}
 	i3.Connect()
 }
+
+var (
+	// storeDir is set by the --store-dir flag and overrides the default snapshot directory.
+	storeDir string
+
+	// retention decides how many older generations of a snapshot are kept; save overrides it with flags.
+	retention = store.DefaultRetention
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&storeDir, "store-dir", "", "directory snapshots are kept in (default $I3_SNAPSHOT_DIR or $XDG_DATA_HOME/i3-snapshot/saves)")
+}
+
+// openStore returns the store all commands read and write snapshots through.
+// It keeps older generations of each snapshot next to the current one.
+func openStore() (*store.HistoryStore, error) {
+	dir, err := snapshotDir()
+	if err != nil {
+		return nil, err
+	}
+	return store.NewHistoryStore(store.NewFileStore(dir), retention), nil
+}
+
+// snapshotDir returns the directory openStore keeps snapshots in.
+func snapshotDir() (string, error) {
+	if storeDir != "" {
+		return storeDir, nil
+	}
+	return store.DefaultDir()
+}
+
+// openMarker returns the clean-shutdown marker kept next to the snapshot called name.
+func openMarker(name string) (*store.SessionMarker, error) {
+	dir, err := snapshotDir()
+	if err != nil {
+		return nil, err
+	}
+	return store.NewSessionMarker(dir, name)
+}
@@ -15,12 +15,42 @@ var saveCmd = &cobra.Command{
 		snapshotName := args[0]
 		fmt.Printf("saving snapshot: %s\n", snapshotName)
 
-		if err := snapshot.Save(snapshotName); err != nil {
+		envAllow, _ := cmd.Flags().GetStringArray("env-allow")
+		envDeny, _ := cmd.Flags().GetStringArray("env-deny")
+		description, _ := cmd.Flags().GetString("description")
+		focused, _ := cmd.Flags().GetBool("focused")
+		workspaces, _ := cmd.Flags().GetStringArray("workspace")
+		outputs, _ := cmd.Flags().GetStringArray("output")
+		opts := snapshot.SaveOptions{
+			EnvAllow:    envAllow,
+			EnvDeny:     envDeny,
+			Description: description,
+			ToolVersion: version,
+			Focused:     focused,
+			Workspaces:  workspaces,
+			Outputs:     outputs,
+		}
+
+		st, err := openStore()
+		if err != nil {
+			fmt.Printf("error saving snapshot: %v\n", err)
+			return
+		}
+
+		if err := snapshot.Save(st, snapshotName, opts); err != nil {
 			fmt.Printf("error saving snapshot: %v\n", err)
 		}
 	},
 }
 
 func init() {
+	saveCmd.Flags().IntVar(&retention.KeepLast, "keep-last", retention.KeepLast, "number of previous generations of the snapshot to keep")
+	saveCmd.Flags().IntVar(&retention.KeepDaily, "keep-daily", retention.KeepDaily, "also keep the newest generation of each of the last N days")
+	saveCmd.Flags().String("description", "", "free text describing the snapshot")
+	saveCmd.Flags().StringArray("env-allow", nil, "also record environment variables matching this pattern, e.g. 'AWS_PROFILE' or 'MY_*' (repeatable)")
+	saveCmd.Flags().StringArray("env-deny", nil, "never record environment variables matching this pattern (repeatable)")
+	saveCmd.Flags().Bool("focused", false, "only save the focused workspace")
+	saveCmd.Flags().StringArray("workspace", nil, "only save the workspace with this name (repeatable)")
+	saveCmd.Flags().StringArray("output", nil, "only save the workspaces on this output (repeatable)")
 	rootCmd.AddCommand(saveCmd)
 }
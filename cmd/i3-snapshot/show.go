@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"github.com/a9sk/i3-snapshot/internal/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show a summary of a saved snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		snap, err := snapshot.Load(st, args[0])
+		if err != nil {
+			return err
+		}
+
+		printSnapshot(snap)
+		return nil
+	},
+}
+
+// printSnapshot prints a human-readable summary of snap: metadata, then every workspace
+// with its windows and the commands used to relaunch them.
+func printSnapshot(snap models.Snapshot) {
+	fmt.Printf("snapshot %s (schema version %d)\n", snap.Name, snap.SchemaVersion)
+	if m := snap.Metadata; m != nil {
+		fmt.Printf("  created:     %s\n", m.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("  host:        %s (user %s)\n", m.Hostname, m.User)
+		fmt.Printf("  i3:          %s\n", m.I3Version)
+		fmt.Printf("  written by:  i3-snapshot %s\n", m.ToolVersion)
+		if m.Description != "" {
+			fmt.Printf("  description: %s\n", m.Description)
+		}
+	}
+	fmt.Printf("  %d workspaces, %d windows\n", len(snap.Workspaces), countWindows(snap))
+
+	for _, ws := range snap.Workspaces {
+		var flags []string
+		if ws.Output != nil {
+			flags = append(flags, "output "+ws.Output.Name)
+		}
+		if ws.Visible {
+			flags = append(flags, "visible")
+		}
+		if ws.Focused {
+			flags = append(flags, "focused")
+		}
+		fmt.Printf("\nworkspace %s", ws.Name)
+		if len(flags) > 0 {
+			fmt.Printf(" (%s)", strings.Join(flags, ", "))
+		}
+		fmt.Println()
+		printWindows(ws.Windows)
+	}
+
+	if snap.Scratchpad != nil {
+		fmt.Printf("\nscratchpad\n")
+		printWindows(snap.Scratchpad.Windows)
+	}
+}
+
+// printWindows prints one line per window plus its command line and working directory.
+func printWindows(windows []models.WindowRef) {
+	if len(windows) == 0 {
+		fmt.Println("  (no windows)")
+		return
+	}
+	for _, w := range windows {
+		fmt.Printf("  [%s/%s] %s\n", w.Class, w.Instance, w.Title)
+		if w.Command != "" {
+			fmt.Printf("    command: %s\n", w.Command)
+		}
+		if w.Machine != "" {
+			fmt.Printf("    remote:  runs on %s, not relaunched\n", w.Machine)
+		}
+		if w.Cwd != "" {
+			fmt.Printf("    cwd:     %s\n", w.Cwd)
+		}
+		if w.Multiplexer != nil {
+			fmt.Printf("    session: %s %s\n", w.Multiplexer.Kind, w.Multiplexer.Session)
+		} else if w.Job != nil {
+			fmt.Printf("    running: %s\n", strings.Join(w.Job.Argv, " "))
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+}
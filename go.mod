@@ -3,10 +3,13 @@ module github.com/a9sk/i3-snapshot
 go 1.25.4
 
 require (
-	github.com/BurntSushi/xgb v0.0.0-20160522181843-27f122750802 // indirect
-	github.com/BurntSushi/xgbutil v0.0.0-20160919175755-f7c97cef3b4e // indirect
+	github.com/BurntSushi/xgb v0.0.0-20160522181843-27f122750802
+	github.com/BurntSushi/xgbutil v0.0.0-20160919175755-f7c97cef3b4e
+	github.com/spf13/cobra v1.10.1
+	go.i3wm.org/i3 v0.0.0-20190720062127-36e6ec85cc5a
+)
+
+require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/cobra v1.10.1 // indirect
 	github.com/spf13/pflag v1.0.10 // indirect
-	go.i3wm.org/i3 v0.0.0-20190720062127-36e6ec85cc5a // indirect
 )
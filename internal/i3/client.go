@@ -7,6 +7,57 @@ import (
 	"go.i3wm.org/i3"
 )
 
+// Client is the i3 IPC as the rest of i3-snapshot uses it. DefaultClient is backed by
+// go.i3wm.org/i3; tests point it at a fake i3 through i3.SocketPathHook (see package i3test)
+// or replace it altogether.
+type Client interface {
+	GetTree() (i3.Tree, error)
+	GetOutputs() ([]i3.Output, error)
+	GetWorkspaces() ([]i3.Workspace, error)
+	GetVersion() (i3.Version, error)
+	RunCommand(command string) ([]i3.CommandResult, error)
+
+	// GetTreeWithState returns the layout tree together with what go.i3wm.org/i3's Node
+	// does not decode, by container ID, both taken from the same GET_TREE reply.
+	GetTreeWithState() (i3.Tree, map[int64]ContainerState, error)
+
+	// Subscribe starts receiving the given event types, like i3.Subscribe.
+	Subscribe(eventTypes ...i3.EventType) EventReceiver
+}
+
+// EventReceiver is the part of *i3.EventReceiver a Client's subscriptions provide.
+type EventReceiver interface {
+	Next() bool
+	Event() i3.Event
+	Err() error
+	Close() error
+}
+
+var _ EventReceiver = (*i3.EventReceiver)(nil)
+
+// libClient is the Client of the running i3.
+type libClient struct {
+	sock socket // for the raw requests go.i3wm.org/i3 does not cover
+}
+
+// DefaultClient returns a client of the running i3.
+func DefaultClient() Client {
+	return &libClient{}
+}
+
+func (c *libClient) GetTree() (i3.Tree, error)              { return i3.GetTree() }
+func (c *libClient) GetOutputs() ([]i3.Output, error)       { return i3.GetOutputs() }
+func (c *libClient) GetWorkspaces() ([]i3.Workspace, error) { return i3.GetWorkspaces() }
+func (c *libClient) GetVersion() (i3.Version, error)        { return i3.GetVersion() }
+
+func (c *libClient) RunCommand(command string) ([]i3.CommandResult, error) {
+	return i3.RunCommand(command)
+}
+
+func (c *libClient) Subscribe(eventTypes ...i3.EventType) EventReceiver {
+	return i3.Subscribe(eventTypes...)
+}
+
 // Connect verifies that we can talk to the i3 IPC socket.
 func Connect() {
 	_ = GetTree()
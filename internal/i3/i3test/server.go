@@ -0,0 +1,1078 @@
+// Package i3test provides a fake i3 for tests: a Server speaking the i3-ipc protocol on a
+// unix socket, with an in-memory layout tree.
+package i3test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.i3wm.org/i3"
+)
+
+// Server is an in-memory stand-in for i3, for integration tests of the save and restore
+// code. It listens on a unix socket in a temporary directory and speaks the i3-ipc wire
+// protocol, so go.i3wm.org/i3 talks to it like to a real i3 once i3.SocketPathHook returns
+// its SocketPath (see Start).
+//
+// The tree has one output per name passed to NewServer, each showing a workspace, and
+// the scratchpad. Of the i3 commands it understands workspace, move workspace to output,
+// append_layout, and kill, focus, move to a workspace and move scratchpad on [con_id=...] or
+// [id=...] criteria (or the focused container); like in i3, criteria matching nothing make
+// the command fail. Any other command succeeds without effect.
+// Applications are simulated with OpenWindow, which swallows the window into a matching
+// append_layout placeholder like i3 does. Workspace and window events are sent to
+// subscribers as the tree changes, shutdown events through Shutdown.
+type Server struct {
+	dir string
+	ln  net.Listener
+	wg  sync.WaitGroup
+
+	mu         sync.Mutex
+	root       *i3.Node
+	lastID     i3.NodeID
+	lastWindow int64
+	swallows   map[i3.NodeID][]swallowCriteria // criteria of every unswallowed placeholder
+	commands   []string
+	conns      map[*client]bool
+}
+
+// client is a client connection to a Server.
+type client struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+	events  map[uint32]bool // event numbers subscribed to, guarded by Server.mu
+}
+
+// event is an event waiting to be sent to the subscribers.
+type event struct {
+	num     uint32
+	payload []byte
+	only    *client // if set, the one subscriber the event is for
+}
+
+// swallowCriteria is a swallows entry of an append_layout file. Like in i3, every
+// non-empty field is a regular expression the window has to match.
+type swallowCriteria struct {
+	Class    string `json:"class,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
+
+// NewServer starts a fake i3 with the given outputs (one called FAKE-1 if none), laid
+// out left to right at 1920x1080 each. The first workspace of the first output is focused.
+// Close stops it.
+func NewServer(outputs ...string) (*Server, error) {
+	if len(outputs) == 0 {
+		outputs = []string{"FAKE-1"}
+	}
+
+	dir, err := os.MkdirTemp("", "i3-snapshot-fake-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating fake i3 socket dir: %w", err)
+	}
+	ln, err := net.Listen("unix", filepath.Join(dir, "ipc.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("listening on fake i3 socket: %w", err)
+	}
+
+	s := &Server{
+		dir:        dir,
+		ln:         ln,
+		lastWindow: 0x1000000,
+		swallows:   make(map[i3.NodeID][]swallowCriteria),
+		conns:      make(map[*client]bool),
+	}
+	s.root = s.newNode(i3.Root, "root")
+
+	internal := s.newNode(i3.OutputNode, "__i3")
+	content := s.newNode(i3.Con, "content")
+	s.attach(s.root, internal, false)
+	s.attach(internal, content, false)
+	s.attach(content, s.newNode(i3.WorkspaceNode, scratchpadWorkspace), false)
+
+	var first *i3.Node
+	for i, name := range outputs {
+		rect := i3.Rect{X: int64(i) * 1920, Width: 1920, Height: 1080}
+		out := s.newNode(i3.OutputNode, name)
+		out.Rect = rect
+		content := s.newNode(i3.Con, "content")
+		content.Rect = rect
+		ws := s.newNode(i3.WorkspaceNode, strconv.Itoa(i+1))
+		ws.Rect = rect
+
+		s.attach(s.root, out, false)
+		s.attach(out, content, false)
+		s.attach(content, ws, false)
+		if first == nil {
+			first = ws
+		}
+	}
+	s.setFocus(first)
+
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// scratchpadWorkspace is the name of the hidden workspace i3 keeps scratchpad windows in.
+const scratchpadWorkspace = "__i3_scratch"
+
+// SocketPath returns the path of the socket the server listens on, e.g. for $I3SOCK.
+func (s *Server) SocketPath() string {
+	return s.ln.Addr().String()
+}
+
+// Commands returns every command string received so far, in order.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Tree returns a copy of the current tree.
+func (s *Server) Tree() (i3.Tree, error) {
+	s.mu.Lock()
+	data, err := json.Marshal(s.root)
+	s.mu.Unlock()
+	if err != nil {
+		return i3.Tree{}, fmt.Errorf("copying fake i3 tree: %w", err)
+	}
+
+	var root i3.Node
+	if err := json.Unmarshal(data, &root); err != nil {
+		return i3.Tree{}, fmt.Errorf("copying fake i3 tree: %w", err)
+	}
+	return i3.Tree{Root: &root}, nil
+}
+
+// OpenWindow simulates an application mapping a window. Like i3, it swallows the window into
+// the first placeholder whose criteria match, or else opens it on the focused workspace and
+// focuses it. It returns the window's container.
+func (s *Server) OpenWindow(class, instance, title string) i3.NodeID {
+	s.mu.Lock()
+	s.lastWindow++
+	props := i3.WindowProperties{Class: class, Instance: instance, Title: title}
+
+	con := s.findPlaceholder(props)
+	if con != nil {
+		delete(s.swallows, con.ID)
+	} else {
+		con = s.newNode(i3.Con, "")
+		s.attach(s.focusedWorkspace(), con, false)
+		s.setFocus(con)
+	}
+	con.Name = title
+	con.Window = s.lastWindow
+	con.WindowProperties = props
+
+	events := []event{s.windowEvent("new", con)}
+	if con.Focused {
+		events = append(events, s.windowEvent("focus", con))
+	}
+	s.mu.Unlock()
+
+	s.broadcast(events)
+	return con.ID
+}
+
+// Shutdown sends a shutdown event with change "exit" or "restart" to the subscribers.
+func (s *Server) Shutdown(change string) {
+	payload, _ := json.Marshal(i3.ShutdownEvent{Change: change})
+	s.broadcast([]event{{num: eventNumbers[i3.ShutdownEventType], payload: payload}})
+}
+
+// Close stops the server, closes all connections and removes the socket.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+
+	s.mu.Lock()
+	for c := range s.conns {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	os.RemoveAll(s.dir)
+	return err
+}
+
+// serve accepts connections until the listener is closed.
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		c := &client{conn: conn, events: make(map[uint32]bool)}
+		s.mu.Lock()
+		s.conns[c] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handleConn(c)
+	}
+}
+
+// handleConn answers the messages of one connection until it is closed.
+func (s *Server) handleConn(c *client) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.conn.Close()
+	}()
+
+	for {
+		msgType, payload, err := readMessage(c.conn)
+		if err != nil {
+			return
+		}
+		reply, events := s.handle(c, msgType, payload)
+		if err := c.send(msgType, reply); err != nil {
+			return
+		}
+		s.broadcast(events)
+	}
+}
+
+// send writes one message to the connection.
+func (c *client) send(msgType uint32, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return writeMessage(c.conn, msgType, payload)
+}
+
+// broadcast sends events to the connections subscribed to them.
+func (s *Server) broadcast(events []event) {
+	for _, ev := range events {
+		var subscribers []*client
+		s.mu.Lock()
+		for c := range s.conns {
+			if c.events[ev.num] && (ev.only == nil || ev.only == c) {
+				subscribers = append(subscribers, c)
+			}
+		}
+		s.mu.Unlock()
+
+		for _, c := range subscribers {
+			c.send(eventMask|ev.num, ev.payload)
+		}
+	}
+}
+
+// handle answers one message and returns the reply and the events it caused.
+func (s *Server) handle(c *client, msgType uint32, payload []byte) ([]byte, []event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var reply any
+	var events []event
+	switch msgType {
+	case messageRunCommand:
+		s.commands = append(s.commands, string(payload))
+		reply, events = s.runCommand(string(payload))
+
+	case messageSubscribe:
+		var names []i3.EventType
+		ok := json.Unmarshal(payload, &names) == nil
+		for _, name := range names {
+			num, known := eventNumbers[name]
+			if !known {
+				ok = false
+				break
+			}
+			c.events[num] = true
+		}
+		reply = map[string]bool{"success": ok}
+		// like i3, confirm a tick subscription with a first tick right after the reply
+		if ok && c.events[eventNumbers[i3.TickEventType]] {
+			payload, _ := json.Marshal(map[string]any{"first": true, "payload": ""})
+			events = append(events, event{num: eventNumbers[i3.TickEventType], payload: payload, only: c})
+		}
+
+	case messageGetTree:
+		reply = s.root
+
+	case messageGetOutputs:
+		reply = s.outputs()
+
+	case messageGetWorkspaces:
+		reply = s.workspaces()
+
+	case messageGetVersion:
+		reply = i3.Version{Major: 4, Minor: 22, HumanReadable: "4.22 (i3-snapshot fake server)"}
+
+	default:
+		reply = map[string]any{"success": false, "error": fmt.Sprintf("unsupported message type %d", msgType)}
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		data = []byte(`{"success":false}`)
+	}
+	return data, events
+}
+
+// newNode creates a container with a fresh ID.
+func (s *Server) newNode(t i3.NodeType, name string) *i3.Node {
+	s.lastID++
+	n := &i3.Node{ID: s.lastID, Type: t, Name: name, Border: i3.NormalBorder}
+	switch t {
+	case i3.OutputNode:
+		n.Layout = i3.OutputLayout
+	case i3.Con, i3.WorkspaceNode:
+		n.Layout = i3.SplitH
+	}
+	return n
+}
+
+// attach adds child to parent as the least recently focused child.
+func (s *Server) attach(parent, child *i3.Node, floating bool) {
+	if floating {
+		parent.FloatingNodes = append(parent.FloatingNodes, child)
+	} else {
+		parent.Nodes = append(parent.Nodes, child)
+	}
+	parent.Focus = append(parent.Focus, child.ID)
+}
+
+// detach removes n from its parent and returns the parent, or nil if n is not in the tree.
+func (s *Server) detach(n *i3.Node) *i3.Node {
+	parent := s.parentOf(n)
+	if parent == nil {
+		return nil
+	}
+	parent.Nodes = removeNode(parent.Nodes, n)
+	parent.FloatingNodes = removeNode(parent.FloatingNodes, n)
+	for i, id := range parent.Focus {
+		if id == n.ID {
+			parent.Focus = append(parent.Focus[:i], parent.Focus[i+1:]...)
+			break
+		}
+	}
+	return parent
+}
+
+func removeNode(nodes []*i3.Node, n *i3.Node) []*i3.Node {
+	for i := range nodes {
+		if nodes[i] == n {
+			return append(nodes[:i], nodes[i+1:]...)
+		}
+	}
+	return nodes
+}
+
+// pathTo returns the nodes from the root down to n, or nil if n is not in the tree.
+func (s *Server) pathTo(n *i3.Node) []*i3.Node {
+	var path []*i3.Node
+	var walk func(cur *i3.Node) bool
+	walk = func(cur *i3.Node) bool {
+		path = append(path, cur)
+		if cur == n {
+			return true
+		}
+		for _, children := range [][]*i3.Node{cur.Nodes, cur.FloatingNodes} {
+			for _, child := range children {
+				if walk(child) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if walk(s.root) {
+		return path
+	}
+	return nil
+}
+
+// parentOf returns the parent of n, or nil.
+func (s *Server) parentOf(n *i3.Node) *i3.Node {
+	path := s.pathTo(n)
+	if len(path) < 2 {
+		return nil
+	}
+	return path[len(path)-2]
+}
+
+// workspaceOf returns the workspace n is on, or nil.
+func (s *Server) workspaceOf(n *i3.Node) *i3.Node {
+	path := s.pathTo(n)
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].Type == i3.WorkspaceNode {
+			return path[i]
+		}
+	}
+	return nil
+}
+
+// outputOf returns the output n is on, or nil.
+func (s *Server) outputOf(n *i3.Node) *i3.Node {
+	for _, p := range s.pathTo(n) {
+		if p.Type == i3.OutputNode {
+			return p
+		}
+	}
+	return nil
+}
+
+// walk calls fn for every node in tree order, tiling children before floating ones.
+func (s *Server) walk(fn func(n *i3.Node)) {
+	var walk func(n *i3.Node)
+	walk = func(n *i3.Node) {
+		fn(n)
+		for _, child := range n.Nodes {
+			walk(child)
+		}
+		for _, child := range n.FloatingNodes {
+			walk(child)
+		}
+	}
+	walk(s.root)
+}
+
+// find returns the first node fn accepts, or nil.
+func (s *Server) find(fn func(n *i3.Node) bool) *i3.Node {
+	var found *i3.Node
+	s.walk(func(n *i3.Node) {
+		if found == nil && fn(n) {
+			found = n
+		}
+	})
+	return found
+}
+
+// focused returns the focused container.
+func (s *Server) focused() *i3.Node {
+	return s.find(func(n *i3.Node) bool { return n.Focused })
+}
+
+// focusedWorkspace returns the workspace holding the focus.
+func (s *Server) focusedWorkspace() *i3.Node {
+	if f := s.focused(); f != nil {
+		if ws := s.workspaceOf(f); ws != nil {
+			return ws
+		}
+	}
+	return s.find(func(n *i3.Node) bool {
+		return n.Type == i3.WorkspaceNode && n.Name != scratchpadWorkspace
+	})
+}
+
+// workspace returns the workspace called name, or nil.
+func (s *Server) workspace(name string) *i3.Node {
+	return s.find(func(n *i3.Node) bool { return n.Type == i3.WorkspaceNode && n.Name == name })
+}
+
+// content returns the content container of an output.
+func content(output *i3.Node) *i3.Node {
+	for _, n := range output.Nodes {
+		if n.Type == i3.Con && n.Name == "content" {
+			return n
+		}
+	}
+	return nil
+}
+
+// setFocus focuses n and makes it the most recently focused child all the way up.
+func (s *Server) setFocus(n *i3.Node) {
+	s.walk(func(other *i3.Node) { other.Focused = false })
+	n.Focused = true
+
+	path := s.pathTo(n)
+	for i := 0; i < len(path)-1; i++ {
+		parent, child := path[i], path[i+1]
+		focus := []i3.NodeID{child.ID}
+		for _, id := range parent.Focus {
+			if id != child.ID {
+				focus = append(focus, id)
+			}
+		}
+		parent.Focus = focus
+	}
+}
+
+// focusTarget returns the container that gets the focus when n is focused: the most
+// recently focused descendant.
+func focusTarget(n *i3.Node) *i3.Node {
+	for len(n.Focus) > 0 {
+		var next *i3.Node
+		for _, children := range [][]*i3.Node{n.Nodes, n.FloatingNodes} {
+			for _, child := range children {
+				if child.ID == n.Focus[0] {
+					next = child
+				}
+			}
+		}
+		if next == nil {
+			break
+		}
+		n = next
+	}
+	return n
+}
+
+// visible reports whether workspace ws is shown on its output.
+func (s *Server) visible(ws *i3.Node) bool {
+	parent := s.parentOf(ws)
+	return parent != nil && len(parent.Focus) > 0 && parent.Focus[0] == ws.ID
+}
+
+// outputs answers GET_OUTPUTS.
+func (s *Server) outputs() []i3.Output {
+	var outputs []i3.Output
+	for _, out := range s.root.Nodes {
+		if out.Type != i3.OutputNode || out.Name == "__i3" {
+			continue
+		}
+		o := i3.Output{Name: out.Name, Active: true, Primary: len(outputs) == 0, Rect: out.Rect}
+		if c := content(out); c != nil {
+			for _, ws := range c.Nodes {
+				if len(c.Focus) > 0 && c.Focus[0] == ws.ID {
+					o.CurrentWorkspace = ws.Name
+				}
+			}
+		}
+		outputs = append(outputs, o)
+	}
+	return outputs
+}
+
+// workspaces answers GET_WORKSPACES.
+func (s *Server) workspaces() []i3.Workspace {
+	focused := s.focusedWorkspace()
+	var workspaces []i3.Workspace
+	s.walk(func(n *i3.Node) {
+		if n.Type != i3.WorkspaceNode || n.Name == scratchpadWorkspace {
+			return
+		}
+		num := int64(-1)
+		if digits := len(n.Name) - len(strings.TrimLeft(n.Name, "0123456789")); digits > 0 {
+			num, _ = strconv.ParseInt(n.Name[:digits], 10, 64)
+		}
+		ws := i3.Workspace{
+			ID:      i3.WorkspaceID(n.ID),
+			Num:     num,
+			Name:    n.Name,
+			Visible: s.visible(n),
+			Focused: n == focused,
+			Rect:    n.Rect,
+		}
+		if out := s.outputOf(n); out != nil {
+			ws.Output = out.Name
+		}
+		workspaces = append(workspaces, ws)
+	})
+	return workspaces
+}
+
+// windowEvent encodes a window event about con.
+func (s *Server) windowEvent(change string, con *i3.Node) event {
+	payload, _ := json.Marshal(map[string]any{"change": change, "container": con})
+	return event{num: eventNumbers[i3.WindowEventType], payload: payload}
+}
+
+// workspaceEvent encodes a workspace event; old may be nil.
+func (s *Server) workspaceEvent(change string, current, old *i3.Node) event {
+	payload, _ := json.Marshal(map[string]any{"change": change, "current": current, "old": old})
+	return event{num: eventNumbers[i3.WorkspaceEventType], payload: payload}
+}
+
+// findPlaceholder returns the first placeholder whose swallow criteria match a window.
+func (s *Server) findPlaceholder(props i3.WindowProperties) *i3.Node {
+	return s.find(func(n *i3.Node) bool {
+		for _, c := range s.swallows[n.ID] {
+			if criterionMatches(c.Class, props.Class) &&
+				criterionMatches(c.Instance, props.Instance) &&
+				criterionMatches(c.Title, props.Title) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// criterionMatches matches a value against an i3 criterion, a regular expression that
+// matches anything when empty.
+func criterionMatches(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return pattern == value
+	}
+	return re.MatchString(value)
+}
+
+// fakeCommand is one command of a RUN_COMMAND payload: optional criteria and the
+// comma-separated actions applied to the containers they select.
+type fakeCommand struct {
+	criteria map[string]string
+	actions  []string
+}
+
+// runCommand runs a RUN_COMMAND payload and returns one result per command.
+func (s *Server) runCommand(payload string) ([]i3.CommandResult, []event) {
+	var results []i3.CommandResult
+	var events []event
+	for _, cmd := range parseCommands(payload) {
+		targets := s.targets(cmd.criteria)
+		for _, action := range cmd.actions {
+			if cmd.criteria != nil && len(targets) == 0 {
+				results = append(results, i3.CommandResult{Success: false, Error: "No window matches given criteria"})
+				continue
+			}
+			evs, err := s.runAction(targets, cmd.criteria != nil, action)
+			events = append(events, evs...)
+			if err != nil {
+				results = append(results, i3.CommandResult{Success: false, Error: err.Error()})
+			} else {
+				results = append(results, i3.CommandResult{Success: true})
+			}
+		}
+	}
+	return results, events
+}
+
+// targets returns the containers selected by criteria, or the focused one without criteria.
+func (s *Server) targets(criteria map[string]string) []*i3.Node {
+	if criteria == nil {
+		if f := s.focused(); f != nil {
+			return []*i3.Node{f}
+		}
+		return nil
+	}
+
+	var targets []*i3.Node
+	s.walk(func(n *i3.Node) {
+		if n.Type != i3.Con && n.Type != i3.FloatingCon {
+			return
+		}
+		for key, value := range criteria {
+			switch key {
+			case "con_id":
+				if strconv.FormatInt(int64(n.ID), 10) != value {
+					return
+				}
+			case "id":
+				if n.Window == 0 || strconv.FormatInt(n.Window, 10) != value {
+					return
+				}
+			case "class":
+				if n.Window == 0 || !criterionMatches(value, n.WindowProperties.Class) {
+					return
+				}
+			case "instance":
+				if n.Window == 0 || !criterionMatches(value, n.WindowProperties.Instance) {
+					return
+				}
+			default:
+				return
+			}
+		}
+		targets = append(targets, n)
+	})
+	return targets
+}
+
+// runAction runs one action on the targets.
+func (s *Server) runAction(targets []*i3.Node, hasCriteria bool, action string) ([]event, error) {
+	verb, arg, _ := strings.Cut(action, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch {
+	case verb == "workspace" && !hasCriteria:
+		name := unquote(strings.TrimPrefix(arg, "number "))
+		if name == "" {
+			return nil, errors.New("workspace name missing")
+		}
+		return s.switchWorkspace(name), nil
+
+	case verb == "append_layout":
+		return nil, s.appendLayout(unquote(arg))
+
+	case strings.HasPrefix(action, "move workspace to output ") && !hasCriteria:
+		return s.moveWorkspaceToOutput(unquote(strings.TrimPrefix(action, "move workspace to output ")))
+
+	case verb == "kill":
+		var events []event
+		for _, n := range targets {
+			events = append(events, s.kill(n)...)
+		}
+		return events, nil
+
+	case verb == "focus" && arg == "":
+		var events []event
+		for _, n := range targets {
+			s.setFocus(n)
+			events = append(events, s.windowEvent("focus", n))
+		}
+		return events, nil
+
+	case verb == "move" && arg == "scratchpad":
+		var events []event
+		for _, n := range targets {
+			events = append(events, s.move(n, s.workspace(scratchpadWorkspace), true)...)
+		}
+		return events, nil
+
+	case verb == "move":
+		name, ok := moveTarget(arg)
+		if !ok {
+			return nil, nil // geometry and other moves do not change the tree
+		}
+		var events []event
+		for _, n := range targets {
+			ws, created := s.ensureWorkspace(name)
+			if created {
+				events = append(events, s.workspaceEvent("init", ws, nil))
+			}
+			events = append(events, s.move(n, ws, false)...)
+		}
+		return events, nil
+	}
+
+	return nil, nil
+}
+
+// moveTarget returns the workspace a "move [container|window] [to] workspace NAME" goes to.
+func moveTarget(arg string) (string, bool) {
+	arg = strings.TrimPrefix(arg, "container ")
+	arg = strings.TrimPrefix(arg, "window ")
+	arg = strings.TrimPrefix(arg, "to ")
+	name, ok := strings.CutPrefix(arg, "workspace ")
+	if !ok || strings.HasPrefix(name, "to output ") {
+		return "", false
+	}
+	return unquote(strings.TrimPrefix(name, "number ")), true
+}
+
+// ensureWorkspace returns the workspace called name, creating it on the focused output.
+func (s *Server) ensureWorkspace(name string) (*i3.Node, bool) {
+	if ws := s.workspace(name); ws != nil {
+		return ws, false
+	}
+	output := s.outputOf(s.focusedWorkspace())
+	ws := s.newNode(i3.WorkspaceNode, name)
+	ws.Rect = output.Rect
+	s.attach(content(output), ws, false)
+	return ws, true
+}
+
+// switchWorkspace shows and focuses the workspace called name, creating it if needed.
+// The workspace left behind is removed if it is empty and no longer visible.
+func (s *Server) switchWorkspace(name string) []event {
+	old := s.focusedWorkspace()
+	ws, created := s.ensureWorkspace(name)
+
+	var events []event
+	if created {
+		events = append(events, s.workspaceEvent("init", ws, nil))
+	}
+	if ws == old {
+		return events
+	}
+
+	s.setFocus(focusTarget(ws))
+	events = append(events, s.workspaceEvent("focus", ws, old))
+
+	if old != nil && len(old.Nodes) == 0 && len(old.FloatingNodes) == 0 && !s.visible(old) {
+		s.detach(old)
+		events = append(events, s.workspaceEvent("empty", old, nil))
+	}
+	return events
+}
+
+// moveWorkspaceToOutput moves the focused workspace to the output called name.
+func (s *Server) moveWorkspaceToOutput(name string) ([]event, error) {
+	var target *i3.Node
+	for _, out := range s.root.Nodes {
+		if out.Type == i3.OutputNode && out.Name == name {
+			target = out
+		}
+	}
+	if target == nil || name == "__i3" {
+		return nil, fmt.Errorf("no output called %s", name)
+	}
+
+	ws := s.focusedWorkspace()
+	from := s.outputOf(ws)
+	if from == target {
+		return nil, nil
+	}
+
+	s.detach(ws)
+	ws.Rect = target.Rect
+	s.attach(content(target), ws, false)
+	s.setFocus(focusTarget(ws))
+	events := []event{s.workspaceEvent("move", ws, nil)}
+
+	// like i3, never leave an output without a workspace
+	if c := content(from); c != nil && len(c.Nodes) == 0 {
+		for i := 1; ; i++ {
+			if s.workspace(strconv.Itoa(i)) == nil {
+				fresh := s.newNode(i3.WorkspaceNode, strconv.Itoa(i))
+				fresh.Rect = from.Rect
+				s.attach(c, fresh, false)
+				events = append(events, s.workspaceEvent("init", fresh, nil))
+				break
+			}
+		}
+	}
+	return events, nil
+}
+
+// move moves container n to workspace ws, as a floating container if floating is set or
+// n already floats. If n had the focus, the focus stays on the workspace it left.
+func (s *Server) move(n, ws *i3.Node, floating bool) []event {
+	from := s.workspaceOf(n)
+	if from == ws || ws == nil {
+		return nil
+	}
+	hadFocus := containsFocus(n)
+
+	parent := s.detach(n)
+	floating = floating || (parent != nil && n.Type == i3.FloatingCon)
+	s.attach(ws, n, floating)
+	s.pruneEmpty(parent)
+
+	if hadFocus && from != nil {
+		s.setFocus(focusTarget(from))
+	}
+	return []event{s.windowEvent("move", n)}
+}
+
+// kill removes n and everything below it.
+func (s *Server) kill(n *i3.Node) []event {
+	ws := s.workspaceOf(n)
+	hadFocus := containsFocus(n)
+
+	var events []event
+	var forget func(c *i3.Node)
+	forget = func(c *i3.Node) {
+		delete(s.swallows, c.ID)
+		if c.Window != 0 {
+			events = append(events, s.windowEvent("close", c))
+		}
+		for _, child := range c.Nodes {
+			forget(child)
+		}
+		for _, child := range c.FloatingNodes {
+			forget(child)
+		}
+	}
+	forget(n)
+
+	s.pruneEmpty(s.detach(n))
+	if hadFocus && ws != nil {
+		s.setFocus(focusTarget(ws))
+	}
+	return events
+}
+
+// pruneEmpty closes split containers that lost their last child, like i3 does.
+func (s *Server) pruneEmpty(n *i3.Node) {
+	for n != nil && (n.Type == i3.Con || n.Type == i3.FloatingCon) && n.Window == 0 &&
+		len(n.Nodes) == 0 && len(n.FloatingNodes) == 0 && s.swallows[n.ID] == nil {
+		n = s.detach(n)
+	}
+}
+
+// containsFocus reports whether n or one of its descendants is focused.
+func containsFocus(n *i3.Node) bool {
+	if n.Focused {
+		return true
+	}
+	for _, children := range [][]*i3.Node{n.Nodes, n.FloatingNodes} {
+		for _, child := range children {
+			if containsFocus(child) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// layoutNode is a container of an append_layout file.
+type layoutNode struct {
+	Type          string            `json:"type"`
+	Name          string            `json:"name"`
+	Layout        i3.Layout         `json:"layout"`
+	Border        i3.BorderStyle    `json:"border"`
+	Percent       float64           `json:"percent"`
+	Rect          i3.Rect           `json:"rect"`
+	Swallows      []swallowCriteria `json:"swallows"`
+	Nodes         []layoutNode      `json:"nodes"`
+	FloatingNodes []layoutNode      `json:"floating_nodes"`
+}
+
+// appendLayout loads an append_layout file into the focused workspace: containers with
+// swallows become placeholders that OpenWindow fills. Floating containers go to the
+// workspace's floating nodes, wherever they are in the file.
+func (s *Server) appendLayout(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading layout: %w", err)
+	}
+
+	// i3 layout files may contain // comments, JSON does not
+	var clean bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		if !strings.HasPrefix(strings.TrimSpace(scanner.Text()), "//") {
+			clean.Write(scanner.Bytes())
+			clean.WriteByte('\n')
+		}
+	}
+
+	var nodes []layoutNode
+	dec := json.NewDecoder(&clean)
+	for {
+		var n layoutNode
+		if err := dec.Decode(&n); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("parsing layout: %w", err)
+		}
+		nodes = append(nodes, n)
+	}
+
+	ws := s.focusedWorkspace()
+	for _, n := range nodes {
+		s.attach(ws, s.buildLayout(ws, n, n.Type == string(i3.FloatingCon)), n.Type == string(i3.FloatingCon))
+	}
+	return nil
+}
+
+// buildLayout creates the containers of a layout node; floating children are attached to ws.
+func (s *Server) buildLayout(ws *i3.Node, ln layoutNode, floating bool) *i3.Node {
+	t := i3.Con
+	if floating {
+		t = i3.FloatingCon
+	}
+	n := s.newNode(t, ln.Name)
+	if ln.Layout != "" {
+		n.Layout = ln.Layout
+	}
+	if ln.Border != "" {
+		n.Border = ln.Border
+	}
+	n.Percent = ln.Percent
+	n.Rect = ln.Rect
+	if len(ln.Swallows) > 0 {
+		s.swallows[n.ID] = ln.Swallows
+	}
+
+	for _, child := range ln.Nodes {
+		s.attach(n, s.buildLayout(ws, child, false), false)
+	}
+	for _, child := range ln.FloatingNodes {
+		s.attach(ws, s.buildLayout(ws, child, true), true)
+	}
+	return n
+}
+
+// parseCommands splits a RUN_COMMAND payload into commands at ";" and into actions at ",",
+// outside of quotes, and parses the criteria in front of each command.
+func parseCommands(payload string) []fakeCommand {
+	var cmds []fakeCommand
+	for _, stmt := range splitOutsideQuotes(payload, ';') {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" {
+			continue
+		}
+
+		var cmd fakeCommand
+		if strings.HasPrefix(stmt, "[") {
+			end := indexOutsideQuotes(stmt, ']')
+			if end < 0 {
+				end = len(stmt) - 1
+			}
+			cmd.criteria = parseCriteria(stmt[1:end])
+			stmt = stmt[end+1:]
+		}
+		for _, action := range splitOutsideQuotes(stmt, ',') {
+			if action = strings.TrimSpace(action); action != "" {
+				cmd.actions = append(cmd.actions, action)
+			}
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+// parseCriteria parses key="value" pairs separated by spaces.
+func parseCriteria(s string) map[string]string {
+	criteria := make(map[string]string)
+	for _, field := range splitOutsideQuotes(s, ' ') {
+		if key, value, ok := strings.Cut(strings.TrimSpace(field), "="); ok {
+			criteria[key] = unquote(value)
+		}
+	}
+	return criteria
+}
+
+// splitOutsideQuotes splits s at every sep that is not inside double quotes.
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for {
+		i := indexOutsideQuotes(s[start:], sep)
+		if i < 0 {
+			return append(parts, s[start:])
+		}
+		parts = append(parts, s[start:start+i])
+		start += i + 1
+	}
+}
+
+// indexOutsideQuotes returns the index of the first c in s that is not inside double
+// quotes, or -1.
+func indexOutsideQuotes(s string, c byte) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == c && !quoted:
+			return i
+		}
+	}
+	return -1
+}
+
+// unquote strips double quotes and backslash escapes from an i3 command argument.
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
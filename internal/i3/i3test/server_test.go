@@ -0,0 +1,81 @@
+package i3test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.i3wm.org/i3"
+)
+
+func TestSubscribeConfirmsWithFirstTick(t *testing.T) {
+	s := Start(t)
+
+	recv := i3.Subscribe(i3.WindowEventType, i3.TickEventType)
+	defer recv.Close()
+	if !recv.Next() {
+		t.Fatalf("no event: %v", recv.Err())
+	}
+	if tick, ok := recv.Event().(*i3.TickEvent); !ok || !tick.First {
+		t.Fatalf("first event = %#v, want the first tick", recv.Event())
+	}
+
+	con := s.OpenWindow("XTerm", "xterm", "shell")
+	if !recv.Next() {
+		t.Fatalf("no window event: %v", recv.Err())
+	}
+	ev, ok := recv.Event().(*i3.WindowEvent)
+	if !ok || ev.Change != "new" || ev.Container.ID != con {
+		t.Fatalf("event = %#v, want window::new for container %d", recv.Event(), con)
+	}
+}
+
+func TestAppendLayoutSwallowsWindows(t *testing.T) {
+	s := Start(t)
+
+	layout := `// i3-save-tree style comment
+{"type": "con", "nodes": [
+	{"type": "con", "swallows": [{"class": "^XTerm$"}]},
+	{"type": "con", "swallows": [{"class": "^Emacs$"}]}
+]}`
+	path := filepath.Join(t.TempDir(), "layout.json")
+	if err := os.WriteFile(path, []byte(layout), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i3.RunCommand("append_layout " + path); err != nil {
+		t.Fatal(err)
+	}
+
+	con := s.OpenWindow("XTerm", "xterm", "shell")
+	other := s.OpenWindow("firefox", "Navigator", "web")
+
+	tree, err := s.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := tree.Root.Nodes[1].Nodes[0].Nodes[0] // FAKE-1, content, workspace 1
+	if ws.Type != i3.WorkspaceNode || len(ws.Nodes) != 2 {
+		t.Fatalf("workspace 1 = %+v, want the layout and firefox", ws)
+	}
+	split := ws.Nodes[0]
+	if split.Nodes[0].ID != con || split.Nodes[0].Window == 0 {
+		t.Errorf("xterm went to container %d, want the first placeholder %d", con, split.Nodes[0].ID)
+	}
+	if split.Nodes[1].Window != 0 {
+		t.Errorf("second placeholder holds window %d, want none", split.Nodes[1].Window)
+	}
+	if ws.Nodes[1].ID != other {
+		t.Errorf("firefox not opened next to the layout")
+	}
+}
+
+func TestCommandsOnMissingContainersFail(t *testing.T) {
+	s := Start(t)
+
+	if _, err := i3.RunCommand(`[con_id="999"] kill`); err == nil {
+		t.Error("killing a missing container succeeded")
+	}
+	if got := s.Commands(); len(got) != 1 || got[0] != `[con_id="999"] kill` {
+		t.Errorf("Commands() = %q", got)
+	}
+}
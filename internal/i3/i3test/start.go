@@ -0,0 +1,27 @@
+package i3test
+
+import (
+	"testing"
+
+	"go.i3wm.org/i3"
+)
+
+// Start starts a Server with the given outputs and points go.i3wm.org/i3 (and with it
+// i3-snapshot's default client) at it until the test ends, when the server is closed.
+// i3.SocketPathHook is global, so tests using Start must not run in parallel.
+func Start(tb testing.TB, outputs ...string) *Server {
+	tb.Helper()
+
+	s, err := NewServer(outputs...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	hook := i3.SocketPathHook
+	i3.SocketPathHook = func() (string, error) { return s.SocketPath(), nil }
+	tb.Cleanup(func() {
+		i3.SocketPathHook = hook
+		s.Close()
+	})
+	return s
+}
@@ -0,0 +1,70 @@
+package i3test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"go.i3wm.org/i3"
+)
+
+// i3-ipc message types the server answers (see https://i3wm.org/docs/ipc.html)
+const (
+	messageRunCommand    uint32 = 0
+	messageGetWorkspaces uint32 = 1
+	messageSubscribe     uint32 = 2
+	messageGetOutputs    uint32 = 3
+	messageGetTree       uint32 = 4
+	messageGetVersion    uint32 = 7
+)
+
+// eventMask is set in the message type of events; the lower bits are the event number.
+const eventMask uint32 = 1 << 31
+
+// event numbers of the i3-ipc event types, by the name used to subscribe to them
+var eventNumbers = map[i3.EventType]uint32{
+	i3.WorkspaceEventType:       0,
+	i3.OutputEventType:          1,
+	i3.ModeEventType:            2,
+	i3.WindowEventType:          3,
+	i3.BarconfigUpdateEventType: 4,
+	i3.BindingEventType:         5,
+	i3.ShutdownEventType:        6,
+	i3.TickEventType:            7,
+}
+
+// ipcMagic starts every i3-ipc message and reply.
+var ipcMagic = []byte("i3-ipc")
+
+// writeMessage writes one i3-ipc message: magic, payload length, type and payload.
+func writeMessage(w io.Writer, msgType uint32, payload []byte) error {
+	var msg bytes.Buffer
+	msg.Write(ipcMagic)
+	binary.Write(&msg, binary.LittleEndian, uint32(len(payload)))
+	binary.Write(&msg, binary.LittleEndian, msgType)
+	msg.Write(payload)
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return fmt.Errorf("writing i3-ipc message: %w", err)
+	}
+	return nil
+}
+
+// readMessage reads one i3-ipc message and returns its type and payload.
+func readMessage(r io.Reader) (uint32, []byte, error) {
+	header := make([]byte, len(ipcMagic)+8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, fmt.Errorf("reading i3-ipc header: %w", err)
+	}
+	if !bytes.Equal(header[:len(ipcMagic)], ipcMagic) {
+		return 0, nil, fmt.Errorf("invalid i3-ipc magic %q", header[:len(ipcMagic)])
+	}
+
+	length := binary.LittleEndian.Uint32(header[len(ipcMagic):])
+	msgType := binary.LittleEndian.Uint32(header[len(ipcMagic)+4:])
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, fmt.Errorf("reading i3-ipc payload: %w", err)
+	}
+	return msgType, payload, nil
+}
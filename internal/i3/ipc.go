@@ -0,0 +1,133 @@
+package i3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"go.i3wm.org/i3"
+)
+
+// i3-ipc message types we send ourselves (see https://i3wm.org/docs/ipc.html)
+const (
+	messageGetTree uint32 = 4
+)
+
+// ipcMagic starts every i3-ipc message and reply.
+var ipcMagic = []byte("i3-ipc")
+
+// socket is the path of the i3 IPC socket, looked up the first time it is needed.
+type socket struct {
+	once sync.Once
+	path string
+	err  error
+}
+
+// get returns the socket path. It comes from the same i3.SocketPathHook go.i3wm.org/i3
+// uses, so both always talk to the same i3.
+func (s *socket) get() (string, error) {
+	s.once.Do(func() {
+		s.path, s.err = i3.SocketPathHook()
+		if s.err != nil {
+			s.err = fmt.Errorf("getting i3 socket path: %w", s.err)
+		}
+		s.path = strings.TrimSpace(s.path)
+	})
+	return s.path, s.err
+}
+
+// sendMessage sends one i3-ipc message on a fresh connection and returns the reply payload.
+// It is used for the few replies go.i3wm.org/i3 does not decode completely.
+func (s *socket) sendMessage(msgType uint32, payload []byte) ([]byte, error) {
+	path, err := s.get()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to i3 socket %s: %w", path, err)
+	}
+	defer conn.Close()
+
+	var msg bytes.Buffer
+	msg.Write(ipcMagic)
+	binary.Write(&msg, binary.LittleEndian, uint32(len(payload)))
+	binary.Write(&msg, binary.LittleEndian, msgType)
+	msg.Write(payload)
+	if _, err := conn.Write(msg.Bytes()); err != nil {
+		return nil, fmt.Errorf("writing i3-ipc message: %w", err)
+	}
+
+	header := make([]byte, len(ipcMagic)+8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, fmt.Errorf("reading i3-ipc reply header: %w", err)
+	}
+	if !bytes.Equal(header[:len(ipcMagic)], ipcMagic) {
+		return nil, fmt.Errorf("invalid i3-ipc reply magic %q", header[:len(ipcMagic)])
+	}
+
+	length := binary.LittleEndian.Uint32(header[len(ipcMagic):])
+	reply := make([]byte, length)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, fmt.Errorf("reading i3-ipc reply payload: %w", err)
+	}
+	return reply, nil
+}
+
+// ContainerState holds per-container attributes i3 reports in GET_TREE that the
+// go.i3wm.org/i3 Node type does not decode.
+type ContainerState struct {
+	Marks          []string `json:"marks"`
+	FullscreenMode int      `json:"fullscreen_mode"` // 0: none, 1: output, 2: global
+	Sticky         bool     `json:"sticky"`
+	Floating       string   `json:"floating"` // "auto_off", "auto_on", "user_off" or "user_on"
+	TitleFormat    string   `json:"title_format"`
+}
+
+// GetTreeWithState fetches the layout tree over raw IPC and decodes it twice: into the
+// go.i3wm.org/i3 tree, and into the state of every container keyed by container ID. Both
+// come from the same reply, so container IDs always agree.
+func (c *libClient) GetTreeWithState() (i3.Tree, map[int64]ContainerState, error) {
+	reply, err := c.sock.sendMessage(messageGetTree, nil)
+	if err != nil {
+		return i3.Tree{}, nil, err
+	}
+
+	var tree i3.Tree
+	if err := json.Unmarshal(reply, &tree.Root); err != nil {
+		return i3.Tree{}, nil, fmt.Errorf("decoding i3 tree: %w", err)
+	}
+
+	type rawNode struct {
+		ID int64 `json:"id"`
+		ContainerState
+		Nodes         []rawNode `json:"nodes"`
+		FloatingNodes []rawNode `json:"floating_nodes"`
+	}
+
+	var root rawNode
+	if err := json.Unmarshal(reply, &root); err != nil {
+		return i3.Tree{}, nil, fmt.Errorf("decoding i3 tree: %w", err)
+	}
+
+	states := make(map[int64]ContainerState)
+	var walk func(n *rawNode)
+	walk = func(n *rawNode) {
+		states[n.ID] = n.ContainerState
+		for i := range n.Nodes {
+			walk(&n.Nodes[i])
+		}
+		for i := range n.FloatingNodes {
+			walk(&n.FloatingNodes[i])
+		}
+	}
+	walk(&root)
+
+	return tree, states, nil
+}
@@ -1,19 +1,63 @@
 package models
 
+import "time"
+
+// SchemaVersion is the snapshot file format written by this build.
+// Bump it whenever Snapshot, WorkspaceSnapshot, LayoutNode or WindowRef change
+// in a way older files cannot be read as-is, and add a migration for it.
+//
+//	1: original format, no schema_version field, space-joined command only
+//	2: exact argv recorded for each window
+const SchemaVersion = 2
+
 // Snapshot is the top-level structure written to disk.
 // It contains a simplified layout tree and per-window launch information.
 type Snapshot struct {
-	Name       string              `json:"name"`
-	Workspaces []WorkspaceSnapshot `json:"workspaces"` // all workspaces in the snapshot
+	SchemaVersion int                 `json:"schema_version"`
+	Name          string              `json:"name"`
+	Metadata      *Metadata           `json:"metadata,omitempty"`   // where and when it was taken, nil for older snapshots
+	Workspaces    []WorkspaceSnapshot `json:"workspaces"`           // all workspaces in the snapshot
+	Scratchpad    *ScratchpadSnapshot `json:"scratchpad,omitempty"` // windows hidden in the scratchpad, if any
+}
+
+// Metadata describes when, where and how a snapshot was taken.
+type Metadata struct {
+	CreatedAt         time.Time `json:"created_at"`
+	Hostname          string    `json:"hostname,omitempty"`
+	User              string    `json:"user,omitempty"`
+	I3Version         string    `json:"i3_version,omitempty"`         // human readable i3 version (GET_VERSION)
+	OutputFingerprint string    `json:"output_fingerprint,omitempty"` // hash of the active outputs' names and geometry
+	ToolVersion       string    `json:"tool_version,omitempty"`       // i3-snapshot version that wrote the file
+	WorkspaceCount    int       `json:"workspace_count"`
+	WindowCount       int       `json:"window_count"`
+	Description       string    `json:"description,omitempty"` // free text given with save --description
+}
+
+// ScratchpadSnapshot holds the windows that were in i3's scratchpad (the __i3_scratch workspace).
+// They cannot be restored through append_layout, so they are launched, matched and sent
+// back with "move scratchpad" instead.
+type ScratchpadSnapshot struct {
+	Root    LayoutNode  `json:"root"` // the __i3_scratch workspace, each window sits in a floating container
+	Windows []WindowRef `json:"windows"`
 }
 
 // WorkspaceSnapshot represents a single workspace with its layout and windows.
 type WorkspaceSnapshot struct {
 	Name    string      `json:"name"`
+	Output  *OutputRef  `json:"output,omitempty"`  // monitor the workspace was on, nil for older snapshots
+	Visible bool        `json:"visible,omitempty"` // shown on its output when saved
+	Focused bool        `json:"focused,omitempty"` // had the global focus when saved
 	Root    LayoutNode  `json:"root"`
 	Windows []WindowRef `json:"windows"`
 }
 
+// OutputRef describes the output (monitor) a workspace was placed on when saved.
+type OutputRef struct {
+	Name    string `json:"name"`              // RandR output name, e.g. "DP-1" or "eDP-1"
+	Rect    Rect   `json:"rect"`              // output geometry in the X11 screen
+	Primary bool   `json:"primary,omitempty"` // whether it was the primary output
+}
+
 // LayoutNode represents a container in the i3 tree (output, workspace, split, tabbed, etc.).
 // This keeps only the fields we care about for reconstructing geometry and hierarchy.
 type LayoutNode struct {
@@ -28,8 +72,15 @@ type LayoutNode struct {
 	WindowInst    string       `json:"window_instance,omitempty"`
 	WindowTitle   string       `json:"window_title,omitempty"`
 	Focused       bool         `json:"focused,omitempty"`
-	Nodes         []LayoutNode `json:"nodes,omitempty"`          // tiling children
-	FloatingNodes []LayoutNode `json:"floating_nodes,omitempty"` // floating children
+	Focus         []int64      `json:"focus,omitempty"`           // focus stack: child IDs, most recently focused first
+	Percent       float64      `json:"percent,omitempty"`         // share of the parent container
+	Marks         []string     `json:"marks,omitempty"`           // i3 marks, used to jump between windows
+	Fullscreen    int          `json:"fullscreen_mode,omitempty"` // 0: none, 1: output, 2: global
+	Sticky        bool         `json:"sticky,omitempty"`          // floating window shown on every workspace
+	Floating      string       `json:"floating,omitempty"`        // i3 floating state, e.g. "user_on" or "auto_off"
+	TitleFormat   string       `json:"title_format,omitempty"`    // custom title_format, if any
+	Nodes         []LayoutNode `json:"nodes,omitempty"`           // tiling children
+	FloatingNodes []LayoutNode `json:"floating_nodes,omitempty"`  // floating children
 }
 
 // Rect is a simple geometry rectangle.
@@ -46,9 +97,33 @@ type WindowRef struct {
 	Class    string `json:"class,omitempty"`    // X11 class
 	Instance string `json:"instance,omitempty"` // X11 instance
 	Title    string `json:"title,omitempty"`    // window title
+	Role     string `json:"role,omitempty"`     // WM_WINDOW_ROLE, if set
+	Machine  string `json:"machine,omitempty"`  // host of a client running on another machine
+
+	Argv    []string `json:"argv,omitempty"` // exact argument vector from /proc/[pid]/cmdline, used for launching
+	Command string   `json:"command"`        // space-joined command line, for display and older snapshots
+	Cwd     string   `json:"cwd,omitempty"`  // working directory from /proc/[pid]/cwd
+
+	Env map[string]string `json:"env,omitempty"` // allowlisted environment from /proc/[pid]/environ
+
+	Shell *ProcessRef `json:"shell,omitempty"` // shell running inside a terminal window
+	Job   *ProcessRef `json:"job,omitempty"`   // foreground job of that shell, e.g. "nvim main.go"
+
+	Multiplexer *MultiplexerRef `json:"multiplexer,omitempty"` // tmux/screen session the foreground job is attached to
+}
+
+// MultiplexerRef records the tmux or screen session a terminal window was attached to.
+type MultiplexerRef struct {
+	Kind       string `json:"kind"` // "tmux" or "screen"
+	Session    string `json:"session"`
+	SocketName string `json:"socket_name,omitempty"` // tmux -L
+	SocketPath string `json:"socket_path,omitempty"` // tmux -S
+}
 
-	Command string `json:"command"`       // full command line from /proc/[pid]/cmdline
-	Cwd     string `json:"cwd,omitempty"` // working directory from /proc/[pid]/cwd
+// ProcessRef records a process running inside a terminal window.
+type ProcessRef struct {
+	Argv []string `json:"argv"`
+	Cwd  string   `json:"cwd,omitempty"`
 }
 
 // I3LayoutNode is the format i3 expects for append_layout.
@@ -60,6 +135,7 @@ type I3LayoutNode struct {
 	Name          string            `json:"name,omitempty"`
 	Border        string            `json:"border,omitempty"`
 	Rect          Rect              `json:"rect"`
+	Percent       float64           `json:"percent,omitempty"`
 	Swallows      []SwallowCriteria `json:"swallows,omitempty"`
 	Nodes         []I3LayoutNode    `json:"nodes,omitempty"`
 	FloatingNodes []I3LayoutNode    `json:"floating_nodes,omitempty"`
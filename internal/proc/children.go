@@ -0,0 +1,139 @@
+package proc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// procStat holds the fields of /proc/[pid]/stat we care about.
+type procStat struct {
+	pid       int
+	ppid      int
+	pgrp      int
+	session   int
+	ttyNr     int
+	tpgid     int    // foreground process group of the controlling terminal
+	startTime uint64 // clock ticks after boot
+}
+
+// readStat parses /proc/[PID]/stat.
+func (fs FS) readStat(pid int) (procStat, error) {
+	statPath := fs.path(pid, "stat")
+	data, err := os.ReadFile(statPath)
+	if err != nil {
+		return procStat{}, fmt.Errorf("reading %s: %w", statPath, err)
+	}
+
+	// the command name is in parentheses and may itself contain spaces and parentheses,
+	// so everything we need is parsed from after the last ')'
+	s := string(data)
+	end := strings.LastIndexByte(s, ')')
+	if end < 0 {
+		return procStat{}, fmt.Errorf("malformed %s", statPath)
+	}
+	fields := strings.Fields(s[end+1:])
+	if len(fields) < 20 {
+		return procStat{}, fmt.Errorf("malformed %s: %d fields", statPath, len(fields))
+	}
+
+	st := procStat{pid: pid}
+	ints := []*int{&st.ppid, &st.pgrp, &st.session, &st.ttyNr, &st.tpgid}
+	for i, dst := range ints {
+		// fields[0] is the state, the numbers we want follow it
+		v, err := strconv.Atoi(fields[i+1])
+		if err != nil {
+			return procStat{}, fmt.Errorf("parsing %s: %w", statPath, err)
+		}
+		*dst = v
+	}
+	if st.startTime, err = strconv.ParseUint(fields[19], 10, 64); err != nil {
+		return procStat{}, fmt.Errorf("parsing %s: %w", statPath, err)
+	}
+	return st, nil
+}
+
+// GetChildPIDs returns the PIDs of the direct children of the given process.
+// It reads /proc/[PID]/task/*/children and falls back to scanning the parent PID of
+// every process when the kernel does not provide those files.
+func (fs FS) GetChildPIDs(pid int) ([]int, error) {
+	if pid <= 0 {
+		return nil, fmt.Errorf("invalid pid: %d", pid)
+	}
+
+	tasks, err := filepath.Glob(fs.path(pid, "task", "*", "children"))
+	if err == nil && len(tasks) > 0 {
+		var children []int
+		for _, t := range tasks {
+			data, err := os.ReadFile(t)
+			if err != nil {
+				continue
+			}
+			for _, f := range strings.Fields(string(data)) {
+				if child, err := strconv.Atoi(f); err == nil {
+					children = append(children, child)
+				}
+			}
+		}
+		return children, nil
+	}
+
+	// no CONFIG_PROC_CHILDREN: look for processes whose parent is pid
+	entries, err := os.ReadDir(fs.Root())
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", fs.Root(), err)
+	}
+	var children []int
+	for _, e := range entries {
+		candidate, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		if st, err := fs.readStat(candidate); err == nil && st.ppid == pid {
+			children = append(children, candidate)
+		}
+	}
+	return children, nil
+}
+
+// GetTerminalProcesses looks inside the terminal emulator with PID termPID and returns the
+// PID of the shell it runs and of that shell's foreground job. The shell is the child that
+// leads its own session on a tty. job is 0 when the shell itself is in the foreground.
+// A terminal running several shells (tabs, or a server such as gnome-terminal-server,
+// urxvtd or "foot --server" serving several windows) is an error: nothing tells which
+// shell belongs to which window, so callers should fall back to the terminal's own cwd.
+func (fs FS) GetTerminalProcesses(termPID int) (shell int, job int, err error) {
+	children, err := fs.GetChildPIDs(termPID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var shells []procStat
+	for _, child := range children {
+		st, err := fs.readStat(child)
+		if err != nil {
+			continue
+		}
+		if st.ttyNr == 0 || st.session != st.pid {
+			continue // not a shell on a terminal
+		}
+		shells = append(shells, st)
+	}
+	if len(shells) == 0 {
+		return 0, 0, fmt.Errorf("no shell found under pid %d", termPID)
+	}
+	if len(shells) > 1 {
+		return 0, 0, fmt.Errorf("pid %d runs %d shells, cannot tell which one is the window's", termPID, len(shells))
+	}
+	sh := shells[0]
+
+	// the terminal's foreground process group is led by the foreground job
+	if sh.tpgid > 0 && sh.tpgid != sh.pgrp {
+		if _, err := fs.readStat(sh.tpgid); err == nil {
+			job = sh.tpgid
+		}
+	}
+	return sh.pid, job, nil
+}
@@ -0,0 +1,72 @@
+package proc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// writeStat writes the stat file of a fixture process, plus the children of its main task.
+func writeStat(t *testing.T, root string, pid, ppid, pgrp, session, tty, tpgid int, children ...int) {
+	t.Helper()
+	dir := filepath.Join(root, strconv.Itoa(pid))
+	task := filepath.Join(dir, "task", strconv.Itoa(pid))
+	if err := os.MkdirAll(task, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stat := fmt.Sprintf("%d (proc %d) S %d %d %d %d %d%s 0\n", pid, pid, ppid, pgrp, session, tty, tpgid, strings.Repeat(" 0", 13))
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var list []string
+	for _, c := range children {
+		list = append(list, strconv.Itoa(c))
+	}
+	if err := os.WriteFile(filepath.Join(task, "children"), []byte(strings.Join(list, " ")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetTerminalProcesses(t *testing.T) {
+	const tty = 34816
+	root := t.TempDir()
+	// a terminal with one shell running a job in the foreground
+	writeStat(t, root, 100, 1, 100, 100, 0, -1, 101)
+	writeStat(t, root, 101, 100, 101, 101, tty, 102, 102)
+	writeStat(t, root, 102, 101, 102, 101, tty, 102)
+	// a terminal server with two windows, each with its shell
+	writeStat(t, root, 200, 1, 200, 200, 0, -1, 201, 202)
+	writeStat(t, root, 201, 200, 201, 201, tty+1, 201)
+	writeStat(t, root, 202, 200, 202, 202, tty+2, 202)
+	// a terminal whose only child is not on a tty
+	writeStat(t, root, 300, 1, 300, 300, 0, -1, 301)
+	writeStat(t, root, 301, 300, 300, 300, 0, -1)
+
+	fs := NewFS(root)
+	tests := []struct {
+		pid        int
+		shell, job int
+		wantErr    bool
+	}{
+		{pid: 100, shell: 101, job: 102},
+		{pid: 200, wantErr: true},
+		{pid: 300, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.pid), func(t *testing.T) {
+			shell, job, err := fs.GetTerminalProcesses(tt.pid)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GetTerminalProcesses(%d) = %d, %d, want an error", tt.pid, shell, job)
+				}
+				return
+			}
+			if err != nil || shell != tt.shell || job != tt.job {
+				t.Errorf("GetTerminalProcesses(%d) = %d, %d, %v, want %d, %d", tt.pid, shell, job, err, tt.shell, tt.job)
+			}
+		})
+	}
+}
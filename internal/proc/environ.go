@@ -0,0 +1,98 @@
+package proc
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// DefaultEnvAllow lists the environment variables captured by default: things that change
+// how an application behaves per window (virtualenvs, kube contexts, toolchains, profiles, locale).
+var DefaultEnvAllow = []string{
+	"VIRTUAL_ENV",
+	"CONDA_DEFAULT_ENV",
+	"CONDA_PREFIX",
+	"KUBECONFIG",
+	"GOPATH",
+	"GOROOT",
+	"GOFLAGS",
+	"PYTHONPATH",
+	"NODE_ENV",
+	"JAVA_HOME",
+	"MOZ_*",
+	"LANG",
+	"LANGUAGE",
+	"LC_*",
+}
+
+// DefaultEnvDeny lists variables that are never captured, even if allowed: secrets and
+// session-specific values that would be wrong or dangerous to replay after a restart.
+var DefaultEnvDeny = []string{
+	"*TOKEN*",
+	"*SECRET*",
+	"*PASSWORD*",
+	"*_KEY",
+	"DISPLAY",
+	"WAYLAND_DISPLAY",
+	"DBUS_SESSION_BUS_ADDRESS",
+	"SSH_AUTH_SOCK",
+	"I3SOCK",
+	"WINDOWID",
+	"XDG_*",
+}
+
+// EnvFilter selects environment variables by name using shell-style patterns ("MOZ_*").
+// A variable is kept if it matches an Allow pattern and no Deny pattern.
+type EnvFilter struct {
+	Allow []string
+	Deny  []string
+}
+
+// DefaultEnvFilter returns the default filter extended with extra allow and deny patterns.
+func DefaultEnvFilter(allow, deny []string) EnvFilter {
+	return EnvFilter{
+		Allow: append(append([]string(nil), DefaultEnvAllow...), allow...),
+		Deny:  append(append([]string(nil), DefaultEnvDeny...), deny...),
+	}
+}
+
+// Match reports whether the variable called name passes the filter.
+func (f EnvFilter) Match(name string) bool {
+	return matchAny(f.Allow, name) && !matchAny(f.Deny, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GetEnvFromPID returns the environment of the given PID, keeping only the variables that
+// pass filter. It reads /proc/[PID]/environ, which holds the environment the process was
+// started with (later changes made by the process itself are not visible).
+func (fs FS) GetEnvFromPID(pid int, filter EnvFilter) (map[string]string, error) {
+	if pid <= 0 {
+		return nil, fmt.Errorf("invalid pid: %d", pid)
+	}
+
+	environPath := fs.path(pid, "environ")
+	data, err := os.ReadFile(environPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", environPath, err)
+	}
+
+	env := make(map[string]string)
+	// /proc/[pid]/environ is null-byte separated KEY=VALUE pairs
+	for _, kv := range strings.Split(string(data), "\x00") {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !filter.Match(name) {
+			continue
+		}
+		env[name] = value
+	}
+	return env, nil
+}
@@ -0,0 +1,106 @@
+package proc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// WindowFixture holds the properties FakeInspector reports for one window. Empty fields
+// are properties the window does not have.
+type WindowFixture struct {
+	PID           int      `json:"pid,omitempty"`
+	Class         string   `json:"class,omitempty"`
+	Instance      string   `json:"instance,omitempty"`
+	Role          string   `json:"role,omitempty"`
+	ClientMachine string   `json:"client_machine,omitempty"`
+	Command       []string `json:"command,omitempty"`
+}
+
+// FakeInspector is a WindowInspector answering from fixtures, by window ID, instead of
+// asking an X server. Together with an FS rooted at a fake procfs directory it lets the
+// save code run without X11 and without the processes behind the windows.
+type FakeInspector map[uint32]WindowFixture
+
+var _ WindowInspector = FakeInspector(nil)
+
+// LoadFakeInspector reads fixtures from a JSON file: an object mapping decimal window IDs
+// to WindowFixture objects, e.g. {"4194307": {"pid": 1234, "class": "Alacritty"}}.
+func LoadFakeInspector(path string) (FakeInspector, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading window fixtures: %w", err)
+	}
+	var f FakeInspector
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing window fixtures %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// window returns the fixture of a window.
+func (f FakeInspector) window(xid uint32) (WindowFixture, error) {
+	w, ok := f[xid]
+	if !ok {
+		return WindowFixture{}, fmt.Errorf("no such window: 0x%x", xid)
+	}
+	return w, nil
+}
+
+// PID returns the fixture's PID.
+func (f FakeInspector) PID(xid uint32) (int, error) {
+	w, err := f.window(xid)
+	if err != nil {
+		return 0, err
+	}
+	if w.PID == 0 {
+		return 0, fmt.Errorf("_NET_WM_PID property empty for window 0x%x", xid)
+	}
+	return w.PID, nil
+}
+
+// Class returns the fixture's class and instance.
+func (f FakeInspector) Class(xid uint32) (string, string, error) {
+	w, err := f.window(xid)
+	if err != nil {
+		return "", "", err
+	}
+	if w.Class == "" && w.Instance == "" {
+		return "", "", fmt.Errorf("no WM_CLASS property on window 0x%x", xid)
+	}
+	return w.Class, w.Instance, nil
+}
+
+// Role returns the fixture's role.
+func (f FakeInspector) Role(xid uint32) (string, error) {
+	return f.property(xid, "WM_WINDOW_ROLE", func(w WindowFixture) string { return w.Role })
+}
+
+// ClientMachine returns the fixture's client machine.
+func (f FakeInspector) ClientMachine(xid uint32) (string, error) {
+	return f.property(xid, "WM_CLIENT_MACHINE", func(w WindowFixture) string { return w.ClientMachine })
+}
+
+// Command returns the fixture's command.
+func (f FakeInspector) Command(xid uint32) ([]string, error) {
+	w, err := f.window(xid)
+	if err != nil {
+		return nil, err
+	}
+	if len(w.Command) == 0 {
+		return nil, fmt.Errorf("no WM_COMMAND property on window 0x%x", xid)
+	}
+	return w.Command, nil
+}
+
+// property returns a string property of a window, or an error if it is empty.
+func (f FakeInspector) property(xid uint32, name string, get func(WindowFixture) string) (string, error) {
+	w, err := f.window(xid)
+	if err != nil {
+		return "", err
+	}
+	if v := get(w); v != "" {
+		return v, nil
+	}
+	return "", fmt.Errorf("no %s property on window 0x%x", name, xid)
+}
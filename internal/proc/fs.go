@@ -0,0 +1,34 @@
+package proc
+
+import (
+	"path/filepath"
+	"strconv"
+)
+
+// DefaultRoot is where procfs is mounted on a running system.
+const DefaultRoot = "/proc"
+
+// FS reads process information from a procfs mounted at its root. Pointing it at another
+// directory laid out like /proc ([pid]/cmdline, [pid]/cwd, [pid]/environ, [pid]/stat, ...)
+// lets the process lookups run against a fixture instead of the live system.
+type FS struct {
+	root string
+}
+
+// NewFS returns an FS reading from root; an empty root means DefaultRoot.
+func NewFS(root string) FS {
+	return FS{root: root}
+}
+
+// Root returns the directory the FS reads from.
+func (fs FS) Root() string {
+	if fs.root == "" {
+		return DefaultRoot
+	}
+	return fs.root
+}
+
+// path returns the path of a file below the directory of pid.
+func (fs FS) path(pid int, elem ...string) string {
+	return filepath.Join(append([]string{fs.Root(), strconv.Itoa(pid)}, elem...)...)
+}
@@ -3,35 +3,50 @@ package proc
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 )
 
-// GetCommandFromPID returns the command line used to start the process with the given PID.
-// It reads /proc/[PID]/cmdline and converts the null-separated content into a space-separated string.
-func GetCommandFromPID(pid int) (string, error) {
+// GetArgvFromPID returns the exact argument vector used to start the process with the given PID.
+// It reads /proc/[PID]/cmdline and splits the null-separated content, so arguments containing
+// spaces or quotes are preserved as-is.
+func (fs FS) GetArgvFromPID(pid int) ([]string, error) {
 	if pid <= 0 {
-		return "", fmt.Errorf("invalid pid: %d", pid)
+		return nil, fmt.Errorf("invalid pid: %d", pid)
 	}
 
-	cmdlinePath := filepath.Join("/proc", fmt.Sprintf("%d", pid), "cmdline")
+	cmdlinePath := fs.path(pid, "cmdline")
 	data, err := os.ReadFile(cmdlinePath)
 	if err != nil {
-		return "", fmt.Errorf("reading %s: %w", cmdlinePath, err)
+		return nil, fmt.Errorf("reading %s: %w", cmdlinePath, err)
+	}
+
+	// kernel threads and zombies have an empty cmdline
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty cmdline for pid %d", pid)
 	}
 
-	// /proc/[pid]/cmdline is null-byte separated; trim any trailing null and join with spaces
-	parts := strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
-	return strings.Join(parts, " "), nil
+	// /proc/[pid]/cmdline is null-byte separated with a trailing null
+	return strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00"), nil
+}
+
+// GetCommandFromPID returns the command line used to start the process with the given PID
+// as a single space-separated string. It is meant for display only: use GetArgvFromPID
+// when the command has to be executed again.
+func (fs FS) GetCommandFromPID(pid int) (string, error) {
+	argv, err := fs.GetArgvFromPID(pid)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(argv, " "), nil
 }
 
 // GetCWDFromPID returns the current working directory of the given PID by resolving /proc/[PID]/cwd.
-func GetCWDFromPID(pid int) (string, error) {
+func (fs FS) GetCWDFromPID(pid int) (string, error) {
 	if pid <= 0 {
 		return "", fmt.Errorf("invalid pid: %d", pid)
 	}
 
-	cwdPath := filepath.Join("/proc", fmt.Sprintf("%d", pid), "cwd")
+	cwdPath := fs.path(pid, "cwd")
 	dir, err := os.Readlink(cwdPath)
 	if err != nil {
 		return "", fmt.Errorf("reading %s: %w", cwdPath, err)
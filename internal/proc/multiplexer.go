@@ -0,0 +1,145 @@
+package proc
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Multiplexer describes a tmux or screen client process and the session it is attached to.
+type Multiplexer struct {
+	Kind       string // "tmux" or "screen"
+	Session    string // session name
+	SocketName string // tmux -L socket name, if any
+	SocketPath string // tmux -S socket path, if any
+}
+
+// GetMultiplexerFromPID checks whether the process with the given PID is a tmux or screen
+// client and returns the session it is attached to. The session is taken from the client's
+// argv (e.g. "tmux attach -t work", "screen -r work"); for a plain "tmux" the tmux server is
+// asked which session that client is on. That only works for the live system's processes,
+// so with an FS rooted anywhere but DefaultRoot a plain "tmux" is an error.
+func (fs FS) GetMultiplexerFromPID(pid int) (Multiplexer, error) {
+	argv, err := fs.GetArgvFromPID(pid)
+	if err != nil {
+		return Multiplexer{}, err
+	}
+
+	switch filepath.Base(argv[0]) {
+	case "tmux":
+		m := parseTmuxArgv(argv[1:])
+		if m.Session == "" {
+			if fs.Root() != DefaultRoot {
+				return Multiplexer{}, fmt.Errorf("no session name in tmux command line of pid %d, and tmux cannot be asked about processes under %s", pid, fs.Root())
+			}
+			session, err := tmuxClientSession(pid, m)
+			if err != nil {
+				return Multiplexer{}, err
+			}
+			m.Session = session
+		}
+		return m, nil
+	case "screen":
+		m := parseScreenArgv(argv[1:])
+		if m.Session == "" {
+			return Multiplexer{}, fmt.Errorf("no session name in screen command line of pid %d", pid)
+		}
+		return m, nil
+	}
+	return Multiplexer{}, fmt.Errorf("pid %d is not a tmux or screen client", pid)
+}
+
+// parseTmuxArgv extracts the socket and target session from tmux arguments
+// (without argv[0]), e.g. "-L work attach -t main" or "new-session -s main".
+func parseTmuxArgv(args []string) Multiplexer {
+	m := Multiplexer{Kind: "tmux"}
+
+	// global options come before the command; these ones take a value
+	i := 0
+	for ; i < len(args) && strings.HasPrefix(args[i], "-"); i++ {
+		switch args[i] {
+		case "-L", "-S", "-f", "-c", "-T":
+			if i+1 < len(args) {
+				if args[i] == "-L" {
+					m.SocketName = args[i+1]
+				} else if args[i] == "-S" {
+					m.SocketPath = args[i+1]
+				}
+				i++
+			}
+		}
+	}
+
+	// the command's own flags: -t for attach, -s for new-session
+	for ; i < len(args); i++ {
+		if v, ok := flagValue(args, i, "-t"); ok {
+			// a target may name a window and pane too ("work:1.0"), keep the session
+			if end := strings.IndexAny(v, ":."); end >= 0 {
+				v = v[:end]
+			}
+			m.Session = v
+		}
+		if v, ok := flagValue(args, i, "-s"); ok {
+			m.Session = v
+		}
+	}
+	return m
+}
+
+// parseScreenArgv extracts the session name from screen arguments (without argv[0]),
+// e.g. "-r work", "-x work" or "-S work".
+func parseScreenArgv(args []string) Multiplexer {
+	m := Multiplexer{Kind: "screen"}
+	for i := range args {
+		for _, flag := range []string{"-r", "-x", "-S"} {
+			if v, ok := flagValue(args, i, flag); ok && !strings.HasPrefix(v, "-") {
+				m.Session = v
+			}
+		}
+	}
+	return m
+}
+
+// flagValue returns the value of flag at args[i], given either as "-t name" or "-tname".
+func flagValue(args []string, i int, flag string) (string, bool) {
+	if args[i] == flag {
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+		return "", false
+	}
+	if strings.HasPrefix(args[i], flag) && len(args[i]) > len(flag) {
+		return args[i][len(flag):], true
+	}
+	return "", false
+}
+
+// tmuxClientSession asks the tmux server which session the client with the given PID is on.
+func tmuxClientSession(pid int, m Multiplexer) (string, error) {
+	args := append(tmuxSocketArgs(m), "list-clients", "-F", "#{client_pid} #{session_name}")
+	out, err := exec.Command("tmux", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("listing tmux clients: %w", err)
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		clientPID, session, ok := strings.Cut(line, " ")
+		if ok && clientPID == strconv.Itoa(pid) {
+			return session, nil
+		}
+	}
+	return "", fmt.Errorf("tmux client %d not found", pid)
+}
+
+// tmuxSocketArgs returns the tmux global flags selecting the socket of m.
+func tmuxSocketArgs(m Multiplexer) []string {
+	switch {
+	case m.SocketPath != "":
+		return []string{"-S", m.SocketPath}
+	case m.SocketName != "":
+		return []string{"-L", m.SocketName}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package proc
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestParseTmuxArgv(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want Multiplexer
+	}{
+		{"attach", []string{"attach", "-t", "work"}, Multiplexer{Kind: "tmux", Session: "work"}},
+		{"attach to a pane", []string{"attach", "-t", "work:1.0"}, Multiplexer{Kind: "tmux", Session: "work"}},
+		{"attach to a window", []string{"a", "-twork:editor"}, Multiplexer{Kind: "tmux", Session: "work"}},
+		{
+			"new session on a socket",
+			[]string{"-L", "dev", "new-session", "-A", "-s", "main"},
+			Multiplexer{Kind: "tmux", Session: "main", SocketName: "dev"},
+		},
+		{"no session", []string{"-S", "/tmp/sock"}, Multiplexer{Kind: "tmux", SocketPath: "/tmp/sock"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTmuxArgv(tt.args); got != tt.want {
+				t.Errorf("parseTmuxArgv(%q) = %+v, want %+v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMultiplexerFromPIDFixture(t *testing.T) {
+	root := t.TempDir()
+	for pid, argv := range map[int]string{
+		10: "tmux\x00attach\x00-t\x00work\x00",
+		20: "tmux\x00",
+		30: "screen\x00-r\x00mail\x00",
+		40: "bash\x00",
+	} {
+		dir := filepath.Join(root, strconv.Itoa(pid))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(argv), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a tmux on PATH that leaves a trace when it is run
+	bin := t.TempDir()
+	trace := filepath.Join(bin, "ran")
+	if err := os.WriteFile(filepath.Join(bin, "tmux"), []byte("#!/bin/sh\n: >"+trace+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	fs := NewFS(root)
+	tests := []struct {
+		pid     int
+		want    Multiplexer
+		wantErr bool
+	}{
+		{10, Multiplexer{Kind: "tmux", Session: "work"}, false},
+		{20, Multiplexer{}, true},
+		{30, Multiplexer{Kind: "screen", Session: "mail"}, false},
+		{40, Multiplexer{}, true},
+	}
+	for _, tt := range tests {
+		got, err := fs.GetMultiplexerFromPID(tt.pid)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("GetMultiplexerFromPID(%d) = %+v, %v, want %+v (error: %v)", tt.pid, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if _, err := os.Stat(trace); err == nil {
+		t.Error("tmux was run for a fixture process")
+	}
+}
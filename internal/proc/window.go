@@ -0,0 +1,17 @@
+package proc
+
+// WindowInspector reads the properties an X11 client sets on its windows, by window ID (XID).
+// Lookups are best-effort: an error means the property is not available for that window.
+type WindowInspector interface {
+	// PID returns the process that owns the window (_NET_WM_PID).
+	PID(xid uint32) (int, error)
+	// Class returns the window's class and instance (WM_CLASS).
+	Class(xid uint32) (class, instance string, err error)
+	// Role returns the window role (WM_WINDOW_ROLE).
+	Role(xid uint32) (string, error)
+	// ClientMachine returns the host the client runs on (WM_CLIENT_MACHINE).
+	ClientMachine(xid uint32) (string, error)
+	// Command returns the command that started the client (WM_COMMAND), set by few
+	// applications nowadays but the only hint we get for clients on other machines.
+	Command(xid uint32) ([]string, error)
+}
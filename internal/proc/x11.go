@@ -0,0 +1,121 @@
+package proc
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil"
+	"github.com/BurntSushi/xgbutil/ewmh"
+	"github.com/BurntSushi/xgbutil/icccm"
+	"github.com/BurntSushi/xgbutil/xprop"
+)
+
+// X11Inspector is the WindowInspector of the X server in $DISPLAY. It connects on the
+// first lookup and keeps the connection for the following ones; Close releases it.
+type X11Inspector struct {
+	once sync.Once
+	xu   *xgbutil.XUtil
+	err  error // why connecting failed, returned by every lookup
+}
+
+var _ WindowInspector = (*X11Inspector)(nil)
+
+// NewX11Inspector returns an inspector for the X server in $DISPLAY.
+func NewX11Inspector() *X11Inspector {
+	return &X11Inspector{}
+}
+
+// conn returns the X connection, connecting if needed.
+func (x *X11Inspector) conn(xid uint32) (*xgbutil.XUtil, error) {
+	if xid == 0 {
+		return nil, fmt.Errorf("invalid window id: 0")
+	}
+	x.once.Do(func() {
+		x.xu, x.err = xgbutil.NewConn()
+		if x.err != nil {
+			x.err = fmt.Errorf("connecting to X11: %w", x.err)
+		}
+	})
+	return x.xu, x.err
+}
+
+// PID resolves the PID of a window from _NET_WM_PID. It fails if the client did not set
+// the property (skill issues, permissions, or X11 issues).
+func (x *X11Inspector) PID(xid uint32) (int, error) {
+	xu, err := x.conn(xid)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := ewmh.WmPidGet(xu, xproto.Window(xid))
+	if err != nil {
+		return 0, fmt.Errorf("reading _NET_WM_PID of window 0x%x: %w", xid, err)
+	}
+	if pid == 0 {
+		return 0, fmt.Errorf("_NET_WM_PID property empty for window 0x%x", xid)
+	}
+	return int(pid), nil
+}
+
+// Class reads WM_CLASS.
+func (x *X11Inspector) Class(xid uint32) (string, string, error) {
+	xu, err := x.conn(xid)
+	if err != nil {
+		return "", "", err
+	}
+	c, err := icccm.WmClassGet(xu, xproto.Window(xid))
+	if err != nil {
+		return "", "", fmt.Errorf("reading WM_CLASS of window 0x%x: %w", xid, err)
+	}
+	return c.Class, c.Instance, nil
+}
+
+// Role reads WM_WINDOW_ROLE.
+func (x *X11Inspector) Role(xid uint32) (string, error) {
+	xu, err := x.conn(xid)
+	if err != nil {
+		return "", err
+	}
+	role, err := xprop.PropValStr(xprop.GetProperty(xu, xproto.Window(xid), "WM_WINDOW_ROLE"))
+	if err != nil {
+		return "", fmt.Errorf("reading WM_WINDOW_ROLE of window 0x%x: %w", xid, err)
+	}
+	return strings.TrimRight(role, "\x00"), nil
+}
+
+// ClientMachine reads WM_CLIENT_MACHINE.
+func (x *X11Inspector) ClientMachine(xid uint32) (string, error) {
+	xu, err := x.conn(xid)
+	if err != nil {
+		return "", err
+	}
+	host, err := icccm.WmClientMachineGet(xu, xproto.Window(xid))
+	if err != nil {
+		return "", fmt.Errorf("reading WM_CLIENT_MACHINE of window 0x%x: %w", xid, err)
+	}
+	return strings.TrimRight(host, "\x00"), nil
+}
+
+// Command reads WM_COMMAND, a null-separated argv like /proc/[pid]/cmdline.
+func (x *X11Inspector) Command(xid uint32) ([]string, error) {
+	xu, err := x.conn(xid)
+	if err != nil {
+		return nil, err
+	}
+	argv, err := xprop.PropValStrs(xprop.GetProperty(xu, xproto.Window(xid), "WM_COMMAND"))
+	if err != nil {
+		return nil, fmt.Errorf("reading WM_COMMAND of window 0x%x: %w", xid, err)
+	}
+	if len(argv) == 0 || argv[0] == "" {
+		return nil, fmt.Errorf("WM_COMMAND property empty for window 0x%x", xid)
+	}
+	return argv, nil
+}
+
+// Close closes the X connection, if there is one.
+func (x *X11Inspector) Close() {
+	if x.xu != nil {
+		x.xu.Conn().Close()
+	}
+}
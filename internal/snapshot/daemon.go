@@ -0,0 +1,139 @@
+package snapshot
+
+import (
+	"context"
+	"time"
+
+	i3internal "github.com/a9sk/i3-snapshot/internal/i3"
+	"github.com/a9sk/i3-snapshot/internal/store"
+	"go.i3wm.org/i3"
+)
+
+// DaemonOptions configures the autosave daemon.
+type DaemonOptions struct {
+	// Name is the snapshot the daemon saves to; older autosaves are kept as generations.
+	Name string
+	// Debounce is how long the session has to be quiet after a change before saving,
+	// so bursts of events (opening a project, moving windows around) cause a single save.
+	Debounce time.Duration
+	// Save is passed on to every save.
+	Save SaveOptions
+	// Marker, if set, is cleared while the daemon runs and set again when i3 exits or the
+	// daemon is stopped, so a later recover can tell whether the session crashed.
+	Marker *store.SessionMarker
+	// Logf reports saves and non-fatal errors; nil discards them.
+	Logf func(format string, args ...any)
+}
+
+// Daemon autosaves the session whenever i3 reports window or workspace changes, until ctx is
+// cancelled or i3 exits. Changes are debounced, and a final save is made when i3 announces
+// that it is exiting or restarting. After a restart the daemon subscribes again.
+// If i3 dies without announcing it, the session marker is left unset.
+func Daemon(ctx context.Context, st store.SnapshotStore, opts DaemonOptions) error {
+	logf := opts.Logf
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+
+	save := func(reason string) {
+		if err := Save(st, opts.Name, opts.Save); err != nil {
+			logf("autosave (%s) failed: %v\n", reason, err)
+			return
+		}
+		logf("autosaved %s (%s)\n", opts.Name, reason)
+	}
+
+	// start from a fresh save so there is always something to recover
+	save("startup")
+	if opts.Marker != nil {
+		if err := opts.Marker.Clear(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		exited, err := watchEvents(ctx, opts.Save.client(), opts.Debounce, save)
+		if exited || ctx.Err() != nil {
+			if opts.Marker != nil {
+				return opts.Marker.MarkClean()
+			}
+			return nil
+		}
+		if err != nil {
+			logf("i3 event subscription ended: %v\n", err)
+		}
+
+		// i3 restarted or the connection dropped: give i3 a moment and subscribe again
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// watchEvents subscribes to i3 events and calls save after every quiet period following a
+// change, and immediately on shutdown. It returns when the subscription ends: exited is true
+// if i3 announced it is exiting for good (as opposed to restarting).
+func watchEvents(ctx context.Context, client i3internal.Client, debounce time.Duration, save func(reason string)) (exited bool, err error) {
+	recv := client.Subscribe(i3.WindowEventType, i3.WorkspaceEventType, i3.ShutdownEventType)
+	defer recv.Close()
+
+	events := make(chan i3.Event)
+	done := make(chan error, 1)
+	// closed when we return, so the reader never blocks on an event nobody receives
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for recv.Next() {
+			select {
+			case events <- recv.Event():
+			case <-stop:
+				return
+			}
+		}
+		done <- recv.Err()
+	}()
+
+	// the timer only runs while there are unsaved changes
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	pending := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			if pending {
+				save("stopping")
+			}
+			return false, nil
+
+		case err := <-done:
+			// do not lose the changes seen so far, i3 may be gone for good
+			if pending {
+				timer.Stop()
+				save("subscription ended")
+			}
+			return false, err
+
+		case <-timer.C:
+			pending = false
+			save("change")
+
+		case ev := <-events:
+			switch e := ev.(type) {
+			case *i3.ShutdownEvent:
+				timer.Stop()
+				save("i3 " + e.Change)
+				return e.Change == "exit", nil
+			case *i3.WindowEvent:
+				// titles and urgency hints change all the time and do not affect the layout
+				if e.Change == "title" || e.Change == "urgent" {
+					continue
+				}
+			}
+			pending = true
+			timer.Reset(debounce)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	i3internal "github.com/a9sk/i3-snapshot/internal/i3"
+	"go.i3wm.org/i3"
+)
+
+// eventClient is an i3 client whose event subscription replays events and then fails with err.
+type eventClient struct {
+	i3internal.Client
+	events []i3.Event
+	err    error
+}
+
+func (c *eventClient) Subscribe(...i3.EventType) i3internal.EventReceiver {
+	return &eventReceiver{events: c.events, err: c.err}
+}
+
+type eventReceiver struct {
+	events []i3.Event
+	ev     i3.Event
+	err    error
+}
+
+func (r *eventReceiver) Next() bool {
+	if len(r.events) == 0 {
+		return false
+	}
+	r.ev, r.events = r.events[0], r.events[1:]
+	return true
+}
+
+func (r *eventReceiver) Event() i3.Event { return r.ev }
+func (r *eventReceiver) Err() error      { return r.err }
+func (r *eventReceiver) Close() error    { return nil }
+
+func TestWatchEvents(t *testing.T) {
+	errLost := errors.New("connection lost")
+	tests := []struct {
+		name       string
+		client     *eventClient
+		wantSaves  []string
+		wantExited bool
+		wantErr    error
+	}{
+		{
+			name: "exit",
+			client: &eventClient{events: []i3.Event{
+				&i3.WindowEvent{Change: "new"},
+				&i3.ShutdownEvent{Change: "exit"},
+				&i3.WindowEvent{Change: "close"},
+			}},
+			wantSaves:  []string{"i3 exit"},
+			wantExited: true,
+		},
+		{
+			name: "restart",
+			client: &eventClient{events: []i3.Event{
+				&i3.ShutdownEvent{Change: "restart"},
+			}},
+			wantSaves: []string{"i3 restart"},
+		},
+		{
+			name: "subscription fails with unsaved changes",
+			client: &eventClient{
+				events: []i3.Event{&i3.WorkspaceEvent{Change: "focus"}},
+				err:    errLost,
+			},
+			wantSaves: []string{"subscription ended"},
+			wantErr:   errLost,
+		},
+		{
+			name: "subscription fails after title changes only",
+			client: &eventClient{
+				events: []i3.Event{&i3.WindowEvent{Change: "title"}},
+				err:    errLost,
+			},
+			wantErr: errLost,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saves []string
+			save := func(reason string) { saves = append(saves, reason) }
+
+			// a debounce this long never fires during the test
+			exited, err := watchEvents(context.Background(), tt.client, time.Hour, save)
+			if exited != tt.wantExited || !errors.Is(err, tt.wantErr) {
+				t.Errorf("watchEvents() = %v, %v, want %v, %v", exited, err, tt.wantExited, tt.wantErr)
+			}
+			if !slices.Equal(saves, tt.wantSaves) {
+				t.Errorf("saves = %q, want %q", saves, tt.wantSaves)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+)
+
+// printPlan writes what Restore would do, in order, without doing any of it: every i3
+// command (with the generated append_layout JSON) and every process launch with its argv,
+// working directory and saved environment. Containers that only exist once the windows
+// have appeared are shown as <window class/instance>.
+func printPlan(w io.Writer, plan Plan) error {
+	for _, step := range plan.Steps {
+		if _, err := fmt.Fprintln(w, step.Describe()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
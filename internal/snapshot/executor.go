@@ -0,0 +1,436 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	i3internal "github.com/a9sk/i3-snapshot/internal/i3"
+	"go.i3wm.org/i3"
+)
+
+// Without i3 events, relaunched windows are found by polling the tree, and these delays
+// give i3 and the applications time to catch up. With events nothing needs to wait: i3
+// has processed a command when it replies, and windows are matched as they appear.
+const (
+	// settleDelay gives i3 time to process workspace switches and create layout placeholders.
+	settleDelay = 200 * time.Millisecond
+	// pollInterval is how often the tree is checked for relaunched windows.
+	pollInterval = 200 * time.Millisecond
+	// warmupDelay gives slow apps a bit more time to fully initialize once their window is there.
+	warmupDelay = 500 * time.Millisecond
+)
+
+// Executor performs a Plan against i3.
+// Failing to switch workspaces or to append a layout aborts the plan; everything else is
+// best-effort, so one misbehaving window does not stop the rest of the restore.
+type Executor struct {
+	I3 i3internal.Client
+
+	// Start starts a launched process without waiting for it; nil uses (*exec.Cmd).Start.
+	Start func(cmd *exec.Cmd) error
+
+	// Progress, if set, is called before each step.
+	Progress func(done, total int, step Step)
+
+	matched map[int64]matchedWindow // container each saved window was matched to, by saved node ID
+	taken   map[i3.NodeID]bool      // containers that cannot be matched (anymore)
+	located map[i3.NodeID]string    // workspace of each container at the last tree fetch
+	watch   *windowWatch            // window events since the plan started, nil if unavailable
+}
+
+// matchedWindow is where a relaunched window showed up.
+type matchedWindow struct {
+	con       i3.NodeID
+	workspace string // empty if not known, e.g. after the window moved
+}
+
+// Execute runs the steps of plan in order.
+func (e *Executor) Execute(plan Plan) error {
+	e.matched = make(map[int64]matchedWindow)
+	e.taken = make(map[i3.NodeID]bool, len(plan.Existing))
+	for _, con := range plan.Existing {
+		e.taken[con] = true
+	}
+
+	// subscribe before anything is launched, so no window can show up unnoticed;
+	// without events, windows are found by polling the tree
+	if watch, err := watchWindows(e.I3); err == nil {
+		e.watch = watch
+		defer func() {
+			watch.Close()
+			e.watch = nil
+		}()
+	}
+
+	for i, step := range plan.Steps {
+		if e.Progress != nil {
+			e.Progress(i, len(plan.Steps), step)
+		}
+		if err := e.run(step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run performs a single step.
+func (e *Executor) run(step Step) error {
+	switch s := step.(type) {
+	case SwitchWorkspace:
+		if _, err := e.I3.RunCommand(fmt.Sprintf("workspace %s", s.Name)); err != nil {
+			return fmt.Errorf("switching to workspace %s: %w", s.Name, err)
+		}
+		// put the workspace back on the monitor it was saved on
+		if s.Output != "" {
+			if _, err := e.I3.RunCommand(fmt.Sprintf("move workspace to output %s", s.Output)); err != nil {
+				return fmt.Errorf("moving workspace %s to output %s: %w", s.Name, s.Output, err)
+			}
+		}
+		e.settle()
+
+	case AppendLayout:
+		if err := e.appendLayout(s); err != nil {
+			return fmt.Errorf("applying layout to workspace %s: %w", s.Workspace, err)
+		}
+		// wait a bit for layout placeholders to be created
+		e.settle()
+
+	case Launch:
+		if err := e.launch(s); err != nil {
+			return err
+		}
+
+	case AwaitWindows:
+		e.await(s)
+
+	case MoveWindow:
+		m, ok := e.matched[s.Window.NodeID]
+		switch {
+		case !ok:
+		case s.Scratchpad:
+			e.I3.RunCommand(conCommand(m.con, "move scratchpad"))
+		case m.workspace != s.Workspace: // also when not known: i3 ignores moves to where a window is
+			e.I3.RunCommand(conCommand(m.con, fmt.Sprintf("move container to workspace %s", s.Workspace)))
+		}
+
+	case KillPlaceholder:
+		e.killPlaceholders(s.Workspace)
+
+	case ApplyGeometry:
+		if m, ok := e.matched[s.Window.NodeID]; ok {
+			e.I3.RunCommand(floatingCommand(m.con, s.Rect, s.Output))
+		}
+
+	case ApplyState:
+		if m, ok := e.matched[s.Window.NodeID]; ok {
+			if cmd := stateCommand(m.con, &s.State); cmd != "" {
+				e.I3.RunCommand(cmd)
+			}
+		}
+
+	case FocusWindow:
+		if m, ok := e.matched[s.Window.NodeID]; ok {
+			e.I3.RunCommand(conCommand(m.con, "focus"))
+		}
+
+	default:
+		return fmt.Errorf("unknown restore step %T", step)
+	}
+	return nil
+}
+
+// appendLayout writes the layout to a temporary file and has i3 load it.
+func (e *Executor) appendLayout(s AppendLayout) error {
+	data, err := layoutJSON(s.Layout)
+	if err != nil {
+		return err
+	}
+
+	// i3 expects a file for append_layout, so we write to a temp file and clean it up
+	tmp, err := os.CreateTemp("", "i3-snapshot-layout-*.json")
+	if err != nil {
+		return fmt.Errorf("creating temp layout file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing temp layout file: %w", err)
+	}
+	tmp.Close()
+
+	if _, err := e.I3.RunCommand(fmt.Sprintf("append_layout %s", tmp.Name())); err != nil {
+		return fmt.Errorf("running append_layout: %w", err)
+	}
+	return nil
+}
+
+// launch starts the process of a Launch step detached from us.
+// Only a step without a command is an error: processes that fail to start do not abort the
+// restore, their window simply never shows up.
+func (e *Executor) launch(s Launch) error {
+	if len(s.Argv) == 0 {
+		return fmt.Errorf("launching window %s/%s: no command", s.Window.Class, s.Window.Instance)
+	}
+	cmd := exec.Command(s.Argv[0], s.Argv[1:]...)
+	cmd.Dir = s.Dir
+	// saved variables go on top of our own environment (the last value wins)
+	if len(s.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range s.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+
+	start := e.Start
+	if start == nil {
+		start = (*exec.Cmd).Start
+	}
+	// detach: we don't need stdout/stderr and don't wait for completion
+	_ = start(cmd)
+	return nil
+}
+
+// await waits until every window of s has been matched to a new container or the timeout
+// has passed. One look at the tree finds the windows that are already there; after that,
+// windows are matched from i3 events as they appear.
+func (e *Executor) await(s AwaitWindows) {
+	pending := make([]WindowTarget, 0, len(s.Windows))
+	for _, w := range s.Windows {
+		if _, ok := e.matched[w.NodeID]; !ok {
+			pending = append(pending, w)
+		}
+	}
+
+	deadline := time.Now().Add(s.Timeout)
+	if len(pending) > 0 {
+		if e.watch != nil {
+			pending = e.awaitEvents(pending, deadline)
+		} else {
+			pending = e.poll(pending, deadline)
+		}
+	}
+
+	// a polled window may not be done setting itself up yet; with events, the window was
+	// matched once i3 managed it and nothing is gained by waiting
+	if len(pending) == 0 && s.Workspace != "" && e.watch == nil {
+		time.Sleep(warmupDelay)
+	}
+}
+
+// settle waits for i3 to catch up with the last command, unless windows are matched from
+// events.
+func (e *Executor) settle() {
+	if e.watch == nil {
+		time.Sleep(settleDelay)
+	}
+}
+
+// awaitEvents matches pending windows from the tree once and then from window events until
+// all of them are matched or the deadline has passed. If the subscription breaks down, the
+// rest of the wait falls back to polling.
+func (e *Executor) awaitEvents(pending []WindowTarget, deadline time.Time) []WindowTarget {
+	// events that arrived before this fetch are already reflected in the tree; the tree
+	// also tells which workspace the layout placeholders that windows get swallowed into are on
+	queued := e.watch.take()
+	if tree, err := e.I3.GetTree(); err == nil {
+		e.located = containerWorkspaces(tree.Root)
+		pending = e.match(tree.Root, pending)
+	} else {
+		pending = e.matchEvents(queued, pending)
+	}
+
+	timeout := time.NewTimer(time.Until(deadline))
+	defer timeout.Stop()
+	for len(pending) > 0 {
+		select {
+		case <-e.watch.ready:
+			pending = e.matchEvents(e.watch.take(), pending)
+		case <-e.watch.done:
+			pending = e.matchEvents(e.watch.take(), pending)
+			e.watch = nil
+			return e.poll(pending, deadline)
+		case <-timeout.C:
+			return pending
+		}
+	}
+	return pending
+}
+
+// matchEvents matches pending windows against window events and returns the saved windows
+// still unmatched. New windows are usually swallowed into a placeholder, whose workspace is
+// known from the last tree fetch; a moved window's workspace is no longer known.
+func (e *Executor) matchEvents(events []*i3.WindowEvent, pending []WindowTarget) []WindowTarget {
+	for _, ev := range events {
+		con := ev.Container.ID
+		switch ev.Change {
+		case "new":
+			if ev.Container.Window != 0 {
+				pending = e.claim(con, ev.Container.WindowProperties, e.located[con], pending)
+			}
+		case "move":
+			delete(e.located, con)
+			for id, m := range e.matched {
+				if m.con == con {
+					e.matched[id] = matchedWindow{con: con}
+				}
+			}
+		}
+	}
+	return pending
+}
+
+// poll fetches the tree until every pending window has been matched or the deadline has
+// passed, and returns the saved windows still unmatched.
+func (e *Executor) poll(pending []WindowTarget, deadline time.Time) []WindowTarget {
+	for len(pending) > 0 && time.Now().Before(deadline) {
+		if tree, err := e.I3.GetTree(); err == nil {
+			pending = e.match(tree.Root, pending)
+		}
+		if len(pending) > 0 {
+			time.Sleep(pollInterval)
+		}
+	}
+	return pending
+}
+
+// match pairs windows in the tree that are not taken yet with pending saved windows and
+// returns the saved windows still unmatched.
+func (e *Executor) match(root *i3.Node, pending []WindowTarget) []WindowTarget {
+	for _, w := range workspaceWindows(root) {
+		pending = e.claim(w.node.ID, w.node.WindowProperties, w.workspace, pending)
+	}
+	return pending
+}
+
+// claim matches the window in container con with the first pending saved window of the
+// same class and instance (titles change too often), unless the container is taken already.
+// It returns the saved windows still unmatched.
+func (e *Executor) claim(con i3.NodeID, wp i3.WindowProperties, workspace string, pending []WindowTarget) []WindowTarget {
+	if e.taken[con] {
+		return pending
+	}
+	for i, expected := range pending {
+		if (expected.Class == "" || wp.Class == expected.Class) &&
+			(expected.Instance == "" || wp.Instance == expected.Instance) {
+			e.taken[con] = true
+			e.matched[expected.NodeID] = matchedWindow{con: con, workspace: workspace}
+			return append(pending[:i], pending[i+1:]...)
+		}
+	}
+	return pending
+}
+
+// killPlaceholders kills the placeholders on a workspace that were never swallowed.
+func (e *Executor) killPlaceholders(workspace string) {
+	tree, err := e.I3.GetTree()
+	if err != nil {
+		return
+	}
+	ws := findWorkspace(tree.Root, workspace)
+	if ws == nil {
+		return
+	}
+
+	placeholders := findPlaceholders(ws)
+	// remove placeholders (in reverse order to avoid ID changes)
+	for i := len(placeholders) - 1; i >= 0; i-- {
+		e.I3.RunCommand(conCommand(placeholders[i].ID, "kill"))
+	}
+}
+
+// placedWindow is a window container together with the name of the workspace it is on.
+type placedWindow struct {
+	node      *i3.Node
+	workspace string
+}
+
+// workspaceWindows returns every window container in the tree with its workspace.
+func workspaceWindows(root *i3.Node) []placedWindow {
+	var windows []placedWindow
+	var walk func(n *i3.Node, workspace string)
+	walk = func(n *i3.Node, workspace string) {
+		if n == nil {
+			return
+		}
+		if n.Type == i3.WorkspaceNode {
+			workspace = n.Name
+		}
+		if n.Window != 0 {
+			windows = append(windows, placedWindow{node: n, workspace: workspace})
+		}
+		for i := range n.Nodes {
+			walk(n.Nodes[i], workspace)
+		}
+		for i := range n.FloatingNodes {
+			walk(n.FloatingNodes[i], workspace)
+		}
+	}
+	walk(root, "")
+	return windows
+}
+
+// containerWorkspaces returns the name of the workspace every container in the tree is on.
+func containerWorkspaces(root *i3.Node) map[i3.NodeID]string {
+	located := make(map[i3.NodeID]string)
+	var walk func(n *i3.Node, workspace string)
+	walk = func(n *i3.Node, workspace string) {
+		if n.Type == i3.WorkspaceNode {
+			workspace = n.Name
+		}
+		if workspace != "" {
+			located[n.ID] = workspace
+		}
+		for i := range n.Nodes {
+			walk(n.Nodes[i], workspace)
+		}
+		for i := range n.FloatingNodes {
+			walk(n.FloatingNodes[i], workspace)
+		}
+	}
+	if root != nil {
+		walk(root, "")
+	}
+	return located
+}
+
+// findWorkspace returns the workspace node called name, or nil.
+func findWorkspace(root *i3.Node, name string) *i3.Node {
+	if root == nil {
+		return nil
+	}
+	if root.Type == i3.WorkspaceNode && root.Name == name {
+		return root
+	}
+	for i := range root.Nodes {
+		if n := findWorkspace(root.Nodes[i], name); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// findPlaceholders returns the placeholder containers below ws that weren't swallowed by
+// real windows: leaf containers without a window (layout containers have children, real
+// windows have Window != 0).
+func findPlaceholders(ws *i3.Node) []*i3.Node {
+	var placeholders []*i3.Node
+	var walk func(n *i3.Node)
+	walk = func(n *i3.Node) {
+		isLeaf := len(n.Nodes) == 0 && len(n.FloatingNodes) == 0
+		if n.Window == 0 && n.Type == i3.Con && isLeaf {
+			placeholders = append(placeholders, n)
+			return
+		}
+		for i := range n.Nodes {
+			walk(n.Nodes[i])
+		}
+		for i := range n.FloatingNodes {
+			walk(n.FloatingNodes[i])
+		}
+	}
+	walk(ws)
+	return placeholders
+}
@@ -0,0 +1,144 @@
+package snapshot
+
+import (
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	i3internal "github.com/a9sk/i3-snapshot/internal/i3"
+	"github.com/a9sk/i3-snapshot/internal/i3/i3test"
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"go.i3wm.org/i3"
+)
+
+func TestExecutor(t *testing.T) {
+	srv := i3test.Start(t, "DP-1")
+	// an open kitty that must not be taken for the relaunched one
+	existing := srv.OpenWindow("kitty", "kitty", "old")
+
+	kitty := WindowTarget{NodeID: 11, Class: "kitty", Instance: "kitty"}
+	ghost := WindowTarget{NodeID: 12, Class: "ghost", Instance: "ghost"}
+	layout := models.I3LayoutNode{Type: "con", Layout: "splith", Nodes: []models.I3LayoutNode{
+		{Type: "con", Swallows: []models.SwallowCriteria{{Class: "kitty", Instance: "kitty"}}},
+		{Type: "con", Swallows: []models.SwallowCriteria{{Class: "ghost", Instance: "ghost"}}},
+	}}
+	plan := Plan{
+		Existing: []i3.NodeID{existing},
+		Steps: []Step{
+			SwitchWorkspace{Name: "2", Output: "DP-1"},
+			AppendLayout{Workspace: "2", Layout: layout},
+			Launch{Window: kitty, Argv: []string{"kitty", "-1"}, Dir: "/src", Env: map[string]string{"EDITOR": "nvim"}},
+			Launch{Window: ghost, Argv: []string{"ghost"}},
+			AwaitWindows{Workspace: "2", Windows: []WindowTarget{kitty, ghost}, Timeout: 300 * time.Millisecond},
+			MoveWindow{Window: kitty, Workspace: "2"},
+			ApplyState{Window: kitty, State: models.LayoutNode{Marks: []string{"term"}}},
+			ApplyState{Window: ghost, State: models.LayoutNode{Marks: []string{"ghost"}}},
+			KillPlaceholder{Workspace: "2"},
+			FocusWindow{Window: kitty},
+		},
+	}
+
+	var started [][]string
+	e := &Executor{
+		I3: i3internal.DefaultClient(),
+		Start: func(cmd *exec.Cmd) error {
+			started = append(started, cmd.Args)
+			switch cmd.Args[0] {
+			case "kitty":
+				if cmd.Dir != "/src" || !slices.Contains(cmd.Env, "EDITOR=nvim") {
+					t.Errorf("kitty started in %q with EDITOR unset", cmd.Dir)
+				}
+				go srv.OpenWindow("kitty", "kitty", "new")
+			case "ghost":
+				// never opens a window
+			}
+			return nil
+		},
+	}
+	if err := e.Execute(plan); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	if want := [][]string{{"kitty", "-1"}, {"ghost"}}; !slices.EqualFunc(started, want, slices.Equal) {
+		t.Errorf("started %q, want %q", started, want)
+	}
+
+	tree, err := srv.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := findWorkspace(tree.Root, "2")
+	if ws == nil {
+		t.Fatal("workspace 2 missing")
+	}
+	windows := workspaceWindows(ws)
+	if len(windows) != 1 || windows[0].node.ID == existing || windows[0].node.WindowProperties.Title != "new" {
+		t.Fatalf("workspace 2 holds %d windows, want only the new kitty", len(windows))
+	}
+	if p := findPlaceholders(ws); len(p) > 0 {
+		t.Errorf("%d placeholders left on workspace 2", len(p))
+	}
+
+	con := windows[0].node.ID
+	commands := srv.Commands()
+	for _, want := range []string{conCommand(con, `mark --add "term"`), conCommand(con, "focus")} {
+		if !slices.Contains(commands, want) {
+			t.Errorf("command %q not run, got:\n%s", want, strings.Join(commands, "\n"))
+		}
+	}
+	for _, cmd := range commands {
+		if strings.Contains(cmd, `"ghost"`) || strings.Contains(cmd, conCommand(existing, "")) {
+			t.Errorf("command %q run for a window that was not relaunched", cmd)
+		}
+	}
+}
+
+func TestExecutorLaunchWithoutCommand(t *testing.T) {
+	i3test.Start(t, "DP-1")
+
+	e := &Executor{
+		I3: i3internal.DefaultClient(),
+		Start: func(cmd *exec.Cmd) error {
+			t.Errorf("started %q", cmd.Args)
+			return nil
+		},
+	}
+	err := e.Execute(Plan{Steps: []Step{Launch{Window: WindowTarget{Class: "kitty"}}}})
+	if err == nil {
+		t.Fatal("Execute succeeded for a launch without a command")
+	}
+}
+
+func TestExecutorWithEventsDoesNotWait(t *testing.T) {
+	srv := i3test.Start(t, "DP-1")
+
+	kitty := WindowTarget{NodeID: 11, Class: "kitty", Instance: "kitty"}
+	layout := models.I3LayoutNode{Type: "con", Nodes: []models.I3LayoutNode{
+		{Type: "con", Swallows: []models.SwallowCriteria{{Class: "kitty"}}},
+	}}
+	plan := Plan{Steps: []Step{
+		SwitchWorkspace{Name: "2"},
+		AppendLayout{Workspace: "2", Layout: layout},
+		Launch{Window: kitty, Argv: []string{"kitty"}},
+		AwaitWindows{Workspace: "2", Windows: []WindowTarget{kitty}, Timeout: windowTimeout},
+		KillPlaceholder{Workspace: "2"},
+	}}
+
+	e := &Executor{
+		I3: i3internal.DefaultClient(),
+		Start: func(cmd *exec.Cmd) error {
+			go srv.OpenWindow("kitty", "kitty", "new")
+			return nil
+		},
+	}
+	start := time.Now()
+	if err := e.Execute(plan); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	// the fixed delays alone would take 2*settleDelay + warmupDelay
+	if elapsed := time.Since(start); elapsed >= warmupDelay {
+		t.Errorf("Execute took %s, want no fixed delays once windows are matched from events", elapsed)
+	}
+}
@@ -0,0 +1,47 @@
+package snapshot
+
+import "github.com/a9sk/i3-snapshot/internal/models"
+
+// focusOrder returns the saved window node IDs below n in the order they have to be focused
+// to rebuild the saved focus stacks: for every container, children are visited from least to
+// most recently focused, so the most recent one is focused last and ends up on top.
+func focusOrder(n *models.LayoutNode) []int64 {
+	if n.WindowID != 0 {
+		return []int64{n.ID}
+	}
+
+	children := make(map[int64]*models.LayoutNode, len(n.Nodes)+len(n.FloatingNodes))
+	for i := range n.Nodes {
+		children[n.Nodes[i].ID] = &n.Nodes[i]
+	}
+	for i := range n.FloatingNodes {
+		children[n.FloatingNodes[i].ID] = &n.FloatingNodes[i]
+	}
+
+	var order []int64
+	// i3's focus array lists the most recently focused child first
+	for i := len(n.Focus) - 1; i >= 0; i-- {
+		if child, ok := children[n.Focus[i]]; ok {
+			order = append(order, focusOrder(child)...)
+		}
+	}
+	return order
+}
+
+// focusedNode returns the ID of the node that had the focus below n, if any.
+func focusedNode(n *models.LayoutNode) (int64, bool) {
+	if n.Focused {
+		return n.ID, true
+	}
+	for i := range n.Nodes {
+		if id, ok := focusedNode(&n.Nodes[i]); ok {
+			return id, true
+		}
+	}
+	for i := range n.FloatingNodes {
+		if id, ok := focusedNode(&n.FloatingNodes[i]); ok {
+			return id, true
+		}
+	}
+	return 0, false
+}
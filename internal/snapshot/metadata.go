@@ -0,0 +1,44 @@
+package snapshot
+
+import (
+	"os"
+	"os/user"
+	"time"
+
+	i3internal "github.com/a9sk/i3-snapshot/internal/i3"
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"go.i3wm.org/i3"
+)
+
+// buildMetadata describes when, where and by what a snapshot was taken.
+// Everything except the counts is best-effort: fields that cannot be determined stay empty.
+func buildMetadata(snap models.Snapshot, outputs []i3.Output, client i3internal.Client, opts SaveOptions) *models.Metadata {
+	meta := &models.Metadata{
+		CreatedAt:         time.Now().UTC(),
+		ToolVersion:       opts.ToolVersion,
+		OutputFingerprint: outputFingerprint(outputs),
+		WorkspaceCount:    len(snap.Workspaces),
+		Description:       opts.Description,
+	}
+
+	for _, ws := range snap.Workspaces {
+		meta.WindowCount += len(ws.Windows)
+	}
+	if snap.Scratchpad != nil {
+		meta.WindowCount += len(snap.Scratchpad.Windows)
+	}
+
+	if host, err := os.Hostname(); err == nil {
+		meta.Hostname = host
+	}
+	if u, err := user.Current(); err == nil {
+		meta.User = u.Username
+	} else {
+		meta.User = os.Getenv("USER")
+	}
+	if v, err := client.GetVersion(); err == nil {
+		meta.I3Version = v.HumanReadable
+	}
+
+	return meta
+}
@@ -0,0 +1,80 @@
+package snapshot
+
+import (
+	"fmt"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+)
+
+// migrations upgrades a snapshot by one schema version: migrations[v] turns a
+// version v snapshot into a version v+1 one. Every bump of models.SchemaVersion
+// needs an entry here so older saves keep loading.
+var migrations = map[int]func(*models.Snapshot) error{
+	1: migrateV1ToV2,
+}
+
+// migrateSnapshot upgrades snap in place to models.SchemaVersion, one version at a time.
+// It reports whether any migration ran and refuses files written by a newer build,
+// since we cannot know which fields we would silently drop.
+func migrateSnapshot(snap *models.Snapshot) (bool, error) {
+	// files written before schema_version existed decode as 0
+	if snap.SchemaVersion == 0 {
+		snap.SchemaVersion = 1
+	}
+
+	if snap.SchemaVersion > models.SchemaVersion {
+		return false, fmt.Errorf("snapshot uses schema version %d, but this build only understands up to version %d; please upgrade i3-snapshot",
+			snap.SchemaVersion, models.SchemaVersion)
+	}
+
+	migrated := false
+	for snap.SchemaVersion < models.SchemaVersion {
+		migrate, ok := migrations[snap.SchemaVersion]
+		if !ok {
+			return false, fmt.Errorf("no migration from schema version %d", snap.SchemaVersion)
+		}
+		if err := migrate(snap); err != nil {
+			return false, fmt.Errorf("migrating schema version %d: %w", snap.SchemaVersion, err)
+		}
+		snap.SchemaVersion++
+		migrated = true
+	}
+
+	return migrated, nil
+}
+
+// migrateV1ToV2 fills in argv for snapshots that only recorded a space-joined command.
+// The original argument boundaries are lost, so this is the same naive split restore
+// used to do: fine for "firefox" or "code /path", wrong for arguments with spaces.
+func migrateV1ToV2(snap *models.Snapshot) error {
+	for i := range snap.Workspaces {
+		windows := snap.Workspaces[i].Windows
+		for j := range windows {
+			if len(windows[j].Argv) == 0 {
+				windows[j].Argv = splitCommandLine(windows[j].Command)
+			}
+		}
+	}
+	return nil
+}
+
+// splitCommandLine is a simple, conservative splitter: it splits on spaces and
+// ignores quoting/escaping.
+func splitCommandLine(cmd string) []string {
+	var out []string
+	cur := ""
+	for _, r := range cmd {
+		if r == ' ' {
+			if cur != "" {
+				out = append(out, cur)
+				cur = ""
+			}
+			continue
+		}
+		cur += string(r)
+	}
+	if cur != "" {
+		out = append(out, cur)
+	}
+	return out
+}
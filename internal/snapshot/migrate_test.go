@@ -0,0 +1,106 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"github.com/a9sk/i3-snapshot/internal/store"
+)
+
+// fixtureStore returns a store holding the snapshot files in testdata under their base name.
+func fixtureStore(t *testing.T, files ...string) store.SnapshotStore {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join("testdata", f+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, f+".json"), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store.NewFileStore(dir)
+}
+
+func TestMigrateFixtures(t *testing.T) {
+	st := fixtureStore(t, "v0", "v1")
+	tests := []struct {
+		file string
+		want map[string][]string // argv by window class
+	}{
+		{"v0", map[string][]string{"Code": {"code", "/home/me/src"}}},
+		// recorded argv is kept as is, only missing ones are split
+		{"v1", map[string][]string{"firefox": {"firefox", "--new-window"}, "mpv": {"mpv", "my video.mkv"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			snap, migrated, err := loadSnapshot(st, tt.file)
+			if err != nil {
+				t.Fatalf("loadSnapshot: %v", err)
+			}
+			if !migrated || snap.SchemaVersion != models.SchemaVersion {
+				t.Errorf("migrated = %v to version %d, want a migration to %d", migrated, snap.SchemaVersion, models.SchemaVersion)
+			}
+			for _, w := range snap.Workspaces[0].Windows {
+				if want := tt.want[w.Class]; !slices.Equal(w.Argv, want) {
+					t.Errorf("%s argv = %q, want %q", w.Class, w.Argv, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateCurrent(t *testing.T) {
+	snap := models.Snapshot{SchemaVersion: models.SchemaVersion}
+	migrated, err := migrateSnapshot(&snap)
+	if err != nil || migrated {
+		t.Errorf("migrateSnapshot() on a current snapshot = %v, %v, want no migration", migrated, err)
+	}
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	snap := models.Snapshot{SchemaVersion: models.SchemaVersion + 1}
+	_, err := migrateSnapshot(&snap)
+	if err == nil || !strings.Contains(err.Error(), "please upgrade") {
+		t.Errorf("migrateSnapshot() on a newer snapshot = %v, want an upgrade error", err)
+	}
+}
+
+func TestMigrateMissingStep(t *testing.T) {
+	saved := migrations
+	t.Cleanup(func() { migrations = saved })
+	migrations = map[int]func(*models.Snapshot) error{}
+
+	snap := models.Snapshot{SchemaVersion: 1, Workspaces: []models.WorkspaceSnapshot{{
+		Windows: []models.WindowRef{{Command: "firefox"}},
+	}}}
+	migrated, err := migrateSnapshot(&snap)
+	if err == nil || migrated {
+		t.Fatalf("migrateSnapshot() without a migration = %v, %v, want an error", migrated, err)
+	}
+	if snap.Workspaces[0].Windows[0].Argv != nil {
+		t.Error("snapshot changed although no migration exists")
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want []string
+	}{
+		{"", nil},
+		{"firefox", []string{"firefox"}},
+		{"  code   /src  ", []string{"code", "/src"}},
+		{`sh -c "echo hi"`, []string{"sh", "-c", `"echo`, `hi"`}}, // quoting is not understood
+	}
+	for _, tt := range tests {
+		if got := splitCommandLine(tt.cmd); !slices.Equal(got, tt.want) {
+			t.Errorf("splitCommandLine(%q) = %q, want %q", tt.cmd, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"go.i3wm.org/i3"
+)
+
+// resolveOutput picks the output a saved workspace should be moved to.
+// It returns the saved output if it is connected and active. Otherwise a workspace that
+// lived on the primary output goes to the current primary output, and anything else
+// stays on whatever output i3 created it on (empty result).
+func resolveOutput(saved *models.OutputRef, outputs []i3.Output) string {
+	if saved == nil || saved.Name == "" {
+		return ""
+	}
+
+	primary := ""
+	for _, o := range outputs {
+		if !o.Active {
+			continue
+		}
+		if o.Name == saved.Name {
+			return o.Name
+		}
+		if o.Primary {
+			primary = o.Name
+		}
+	}
+
+	if saved.Primary {
+		return primary
+	}
+	return ""
+}
+
+// remapOutputs points every workspace of snap at a connected output and rewrites the saved
+// rects of its layout (tiling and floating) proportionally to the new output's geometry.
+// Floating scratchpad windows are scaled along with the output they were on.
+// Explicit overrides (saved name -> target name) win over the automatic mapping, which keeps
+// outputs with the same name and otherwise picks the closest match by resolution and position.
+func remapOutputs(snap *models.Snapshot, outputs []i3.Output, overrides map[string]string) error {
+	var active []i3.Output
+	for _, o := range outputs {
+		if o.Active {
+			active = append(active, o)
+		}
+	}
+	if len(active) == 0 {
+		return nil
+	}
+
+	for saved, target := range overrides {
+		if findOutput(active, target) == nil {
+			return fmt.Errorf("mapping output %s to %s: output %s is not connected", saved, target, target)
+		}
+	}
+
+	// the scratchpad has no output of its own, its windows follow the outputs they were on
+	if snap.Scratchpad != nil {
+		remapScratchpad(snap.Scratchpad, savedOutputs(snap), active, overrides)
+	}
+
+	for i := range snap.Workspaces {
+		ws := &snap.Workspaces[i]
+		if ws.Output == nil || ws.Output.Name == "" {
+			continue
+		}
+
+		target := targetOutput(*ws.Output, active, overrides)
+		from := ws.Output.Rect
+		to := convertRect(target.Rect)
+		scaleLayout(&ws.Root, from, to)
+
+		ws.Output = &models.OutputRef{
+			Name:    target.Name,
+			Rect:    to,
+			Primary: target.Primary,
+		}
+	}
+
+	return nil
+}
+
+// targetOutput returns the connected output a saved output is mapped to: the override if
+// there is one, the closest match otherwise.
+func targetOutput(saved models.OutputRef, active []i3.Output, overrides map[string]string) *i3.Output {
+	if name, ok := overrides[saved.Name]; ok {
+		return findOutput(active, name)
+	}
+	return matchOutput(saved, active)
+}
+
+// savedOutputs returns the outputs the workspaces of snap were saved on, the output of the
+// focused workspace first and then the primary one, so that they are the fallbacks.
+func savedOutputs(snap *models.Snapshot) []models.OutputRef {
+	var outputs []models.OutputRef
+	seen := make(map[string]bool)
+	add := func(o *models.OutputRef) {
+		if o != nil && o.Name != "" && !seen[o.Name] {
+			seen[o.Name] = true
+			outputs = append(outputs, *o)
+		}
+	}
+
+	for _, ws := range snap.Workspaces {
+		if ws.Focused {
+			add(ws.Output)
+		}
+	}
+	for _, ws := range snap.Workspaces {
+		if ws.Output != nil && ws.Output.Primary {
+			add(ws.Output)
+		}
+	}
+	for _, ws := range snap.Workspaces {
+		add(ws.Output)
+	}
+	return outputs
+}
+
+// snapshotOutputs returns the outputs snap was saved on as connected i3 outputs, for
+// planning a restore without i3.
+func snapshotOutputs(snap *models.Snapshot) []i3.Output {
+	var outputs []i3.Output
+	for _, o := range savedOutputs(snap) {
+		outputs = append(outputs, i3.Output{
+			Name:    o.Name,
+			Active:  true,
+			Primary: o.Primary,
+			Rect:    i3.Rect{X: int64(o.Rect.X), Y: int64(o.Rect.Y), Width: int64(o.Rect.Width), Height: int64(o.Rect.Height)},
+		})
+	}
+	return outputs
+}
+
+// remapScratchpad scales every floating scratchpad window from the saved output it was on
+// onto that output's target, like remapOutputs does for workspaces. A window is on the
+// saved output holding its center; one outside all of them (or in a snapshot without
+// outputs) is scaled like the focused, else the primary saved output.
+func remapScratchpad(sp *models.ScratchpadSnapshot, saved []models.OutputRef, active []i3.Output, overrides map[string]string) {
+	if len(saved) == 0 {
+		return
+	}
+
+	for i := range sp.Root.FloatingNodes {
+		n := &sp.Root.FloatingNodes[i]
+		from := saved[0]
+		cx, cy := n.Rect.X+n.Rect.Width/2, n.Rect.Y+n.Rect.Height/2
+		for _, o := range saved {
+			r := o.Rect
+			if cx >= r.X && cx < r.X+r.Width && cy >= r.Y && cy < r.Y+r.Height {
+				from = o
+				break
+			}
+		}
+
+		target := targetOutput(from, active, overrides)
+		scaleLayout(n, from.Rect, convertRect(target.Rect))
+	}
+}
+
+// findOutput returns the output called name, or nil if there is none.
+func findOutput(outputs []i3.Output, name string) *i3.Output {
+	for i := range outputs {
+		if outputs[i].Name == name {
+			return &outputs[i]
+		}
+	}
+	return nil
+}
+
+// matchOutput picks the connected output that best replaces a saved one: the output with the
+// same name if it is still there, otherwise the one with the same resolution closest to the
+// saved position, preferring the primary output on ties. outputs must not be empty.
+func matchOutput(saved models.OutputRef, outputs []i3.Output) *i3.Output {
+	if o := findOutput(outputs, saved.Name); o != nil {
+		return o
+	}
+
+	var best *i3.Output
+	bestScore := 0
+	for i := range outputs {
+		o := &outputs[i]
+		r := convertRect(o.Rect)
+
+		// a different resolution always loses against a matching one
+		score := abs(r.X-saved.Rect.X) + abs(r.Y-saved.Rect.Y)
+		if r.Width != saved.Rect.Width || r.Height != saved.Rect.Height {
+			score += 1 << 30
+		}
+		if o.Primary != saved.Primary {
+			score++
+		}
+
+		if best == nil || score < bestScore {
+			best = o
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// scaleLayout rewrites the rect of n and all its descendants from the coordinate space of
+// the output rect from into the one of the output rect to.
+func scaleLayout(n *models.LayoutNode, from, to models.Rect) {
+	if from == to {
+		return
+	}
+
+	n.Rect = scaleRect(n.Rect, from, to)
+	for i := range n.Nodes {
+		scaleLayout(&n.Nodes[i], from, to)
+	}
+	for i := range n.FloatingNodes {
+		scaleLayout(&n.FloatingNodes[i], from, to)
+	}
+}
+
+// scaleRect maps r, an absolute rect on output from, proportionally onto output to.
+func scaleRect(r, from, to models.Rect) models.Rect {
+	if from.Width == 0 || from.Height == 0 {
+		// no usable saved geometry: keep the size and just move the origin over
+		return models.Rect{X: to.X + r.X - from.X, Y: to.Y + r.Y - from.Y, Width: r.Width, Height: r.Height}
+	}
+
+	return models.Rect{
+		X:      to.X + (r.X-from.X)*to.Width/from.Width,
+		Y:      to.Y + (r.Y-from.Y)*to.Height/from.Height,
+		Width:  r.Width * to.Width / from.Width,
+		Height: r.Height * to.Height / from.Height,
+	}
+}
+
+// outputFingerprint identifies an output configuration: the names and geometry of all
+// active outputs, hashed so that equal setups give equal fingerprints regardless of order.
+func outputFingerprint(outputs []i3.Output) string {
+	var parts []string
+	for _, o := range outputs {
+		if !o.Active {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%dx%d+%d+%d", o.Name, o.Rect.Width, o.Rect.Height, o.Rect.X, o.Rect.Y))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	sort.Strings(parts)
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, ",")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
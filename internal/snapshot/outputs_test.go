@@ -0,0 +1,199 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"go.i3wm.org/i3"
+)
+
+// floatingWindow is a scratchpad floating container holding a window with the given rect.
+func floatingWindow(id int64, r models.Rect) models.LayoutNode {
+	return models.LayoutNode{
+		ID:    id,
+		Type:  string(i3.FloatingCon),
+		Rect:  r,
+		Nodes: []models.LayoutNode{{ID: id + 1, Type: string(i3.Con), Rect: r, WindowID: 1}},
+	}
+}
+
+func TestRemapOutputsScalesScratchpad(t *testing.T) {
+	// saved on a 4K monitor left of a 1080p laptop panel
+	big := models.OutputRef{Name: "DP-1", Rect: models.Rect{Width: 3840, Height: 2160}}
+	laptop := models.OutputRef{Name: "eDP-1", Rect: models.Rect{X: 3840, Width: 1920, Height: 1080}, Primary: true}
+	snap := models.Snapshot{
+		Workspaces: []models.WorkspaceSnapshot{
+			{Name: "1", Output: &big, Focused: true},
+			{Name: "2", Output: &laptop},
+		},
+		Scratchpad: &models.ScratchpadSnapshot{Root: models.LayoutNode{
+			Type: string(i3.WorkspaceNode),
+			FloatingNodes: []models.LayoutNode{
+				floatingWindow(10, models.Rect{X: 1920, Y: 1080, Width: 800, Height: 400}), // on DP-1
+				floatingWindow(20, models.Rect{X: 4840, Y: 100, Width: 400, Height: 200}),  // on eDP-1
+				floatingWindow(30, models.Rect{X: 9000, Y: 100, Width: 400, Height: 200}),  // off screen
+			},
+		}},
+	}
+
+	// now only a 1080p monitor called HDMI-1 next to the laptop panel
+	outputs := []i3.Output{
+		{Name: "HDMI-1", Active: true, Rect: i3.Rect{Width: 1920, Height: 1080}},
+		{Name: "eDP-1", Active: true, Primary: true, Rect: i3.Rect{X: 1920, Width: 1920, Height: 1080}},
+	}
+	if err := remapOutputs(&snap, outputs, map[string]string{"DP-1": "HDMI-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []models.Rect{
+		{X: 960, Y: 540, Width: 400, Height: 200},  // halved onto HDMI-1
+		{X: 2920, Y: 100, Width: 400, Height: 200}, // moved along with eDP-1
+		{X: 4500, Y: 50, Width: 200, Height: 100},  // scaled like the focused workspace's DP-1
+	}
+	for i, n := range snap.Scratchpad.Root.FloatingNodes {
+		if n.Rect != want[i] {
+			t.Errorf("scratchpad window %d at %+v, want %+v", i, n.Rect, want[i])
+		}
+		if n.Nodes[0].Rect != want[i] {
+			t.Errorf("scratchpad window %d content at %+v, want %+v", i, n.Nodes[0].Rect, want[i])
+		}
+	}
+}
+
+func TestRemapOutputsWithoutConnectedOutputs(t *testing.T) {
+	out := models.OutputRef{Name: "DP-1", Rect: models.Rect{Width: 1920, Height: 1080}}
+	snap := models.Snapshot{Workspaces: []models.WorkspaceSnapshot{{Name: "1", Output: &out}}}
+	if err := remapOutputs(&snap, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Workspaces[0].Output.Name != "DP-1" {
+		t.Errorf("output changed to %s without any connected output", snap.Workspaces[0].Output.Name)
+	}
+}
+
+func TestResolveOutput(t *testing.T) {
+	outputs := []i3.Output{
+		{Name: "DP-1", Active: true},
+		{Name: "eDP-1", Active: true, Primary: true},
+		{Name: "HDMI-1", Active: false},
+	}
+
+	cases := []struct {
+		name  string
+		saved *models.OutputRef
+		want  string
+	}{
+		{"no saved output", nil, ""},
+		{"still connected", &models.OutputRef{Name: "DP-1"}, "DP-1"},
+		{"gone primary", &models.OutputRef{Name: "LVDS-1", Primary: true}, "eDP-1"},
+		{"gone", &models.OutputRef{Name: "LVDS-1"}, ""},
+		{"disabled", &models.OutputRef{Name: "HDMI-1"}, ""},
+	}
+	for _, c := range cases {
+		if got := resolveOutput(c.saved, outputs); got != c.want {
+			t.Errorf("%s: resolveOutput() = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestMatchOutput(t *testing.T) {
+	hd := models.Rect{Width: 1920, Height: 1080}
+	left := i3.Output{Name: "HDMI-1", Rect: i3.Rect{Width: 1920, Height: 1080}}
+	laptop := i3.Output{Name: "eDP-1", Primary: true, Rect: i3.Rect{X: 1920, Width: 2560, Height: 1440}}
+	right := i3.Output{Name: "DP-2", Rect: i3.Rect{X: 4480, Width: 1920, Height: 1080}}
+	docked := []i3.Output{left, laptop, right}
+
+	cases := []struct {
+		name    string
+		saved   models.OutputRef
+		outputs []i3.Output
+		want    string
+	}{
+		{"same name, new geometry", models.OutputRef{Name: "eDP-1", Rect: hd}, docked, "eDP-1"},
+		{"same resolution, closest position", models.OutputRef{Name: "DP-1", Rect: models.Rect{X: 4000, Width: 1920, Height: 1080}}, docked, "DP-2"},
+		{"resolution beats position", models.OutputRef{Name: "DP-1", Rect: models.Rect{X: 1920, Width: 2560, Height: 1440}}, []i3.Output{left, right, {Name: "DP-3", Rect: i3.Rect{X: 9000, Width: 2560, Height: 1440}}}, "DP-3"},
+		{"no resolution match, closest position", models.OutputRef{Name: "DP-1", Rect: models.Rect{X: 4480, Width: 3840, Height: 2160}}, docked, "DP-2"},
+		{"tie goes to the primary", models.OutputRef{Name: "DP-1", Rect: models.Rect{X: 1920, Width: 1920, Height: 1080}, Primary: true}, []i3.Output{left, {Name: "DP-2", Primary: true, Rect: i3.Rect{X: 3840, Width: 1920, Height: 1080}}}, "DP-2"},
+		{"tie goes to a non-primary", models.OutputRef{Name: "DP-1", Rect: models.Rect{X: 1920, Width: 1920, Height: 1080}}, []i3.Output{{Name: "DP-2", Primary: true, Rect: i3.Rect{X: 3840, Width: 1920, Height: 1080}}, left}, "HDMI-1"},
+	}
+	for _, c := range cases {
+		if got := matchOutput(c.saved, c.outputs); got == nil || got.Name != c.want {
+			t.Errorf("%s: matchOutput() = %v, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRemapOutputsScalesWorkspace(t *testing.T) {
+	// saved on a 4K monitor that is gone, only a 1080p one is left
+	big := models.OutputRef{Name: "DP-1", Rect: models.Rect{Width: 3840, Height: 2160}}
+	snap := models.Snapshot{Workspaces: []models.WorkspaceSnapshot{{
+		Name:   "1",
+		Output: &big,
+		Root: models.LayoutNode{
+			Type:          string(i3.WorkspaceNode),
+			Rect:          big.Rect,
+			Nodes:         []models.LayoutNode{{ID: 1, Type: string(i3.Con), Rect: models.Rect{X: 1920, Width: 1920, Height: 2160}}},
+			FloatingNodes: []models.LayoutNode{floatingWindow(10, models.Rect{X: 1920, Y: 1080, Width: 800, Height: 400})},
+		},
+	}}}
+	outputs := []i3.Output{{Name: "HDMI-1", Active: true, Rect: i3.Rect{X: 1920, Width: 1920, Height: 1080}}}
+	if err := remapOutputs(&snap, outputs, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := snap.Workspaces[0]
+	want := models.OutputRef{Name: "HDMI-1", Rect: models.Rect{X: 1920, Width: 1920, Height: 1080}}
+	if *ws.Output != want {
+		t.Errorf("workspace moved to %+v, want %+v", *ws.Output, want)
+	}
+	if r := ws.Root.Nodes[0].Rect; r != (models.Rect{X: 2880, Width: 960, Height: 1080}) {
+		t.Errorf("tiling window at %+v, want the right half of HDMI-1", r)
+	}
+	floating := ws.Root.FloatingNodes[0]
+	if want := (models.Rect{X: 2880, Y: 540, Width: 400, Height: 200}); floating.Rect != want || floating.Nodes[0].Rect != want {
+		t.Errorf("floating window at %+v (content %+v), want %+v", floating.Rect, floating.Nodes[0].Rect, want)
+	}
+}
+
+func TestRemapOutputsOverrides(t *testing.T) {
+	laptop := models.OutputRef{Name: "eDP-1", Rect: models.Rect{Width: 1920, Height: 1080}}
+	outputs := []i3.Output{
+		{Name: "eDP-1", Active: true, Rect: i3.Rect{Width: 1920, Height: 1080}},
+		{Name: "DP-1", Active: true, Rect: i3.Rect{X: 1920, Width: 1920, Height: 1080}},
+		{Name: "DP-2", Active: false},
+	}
+
+	// the override wins over the output with the same name
+	snap := models.Snapshot{Workspaces: []models.WorkspaceSnapshot{{Name: "1", Output: &laptop}}}
+	if err := remapOutputs(&snap, outputs, map[string]string{"eDP-1": "DP-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := snap.Workspaces[0].Output.Name; got != "DP-1" {
+		t.Errorf("workspace on %s, want DP-1", got)
+	}
+
+	for _, target := range []string{"DP-2", "DP-3"} {
+		snap := models.Snapshot{Workspaces: []models.WorkspaceSnapshot{{Name: "1", Output: &laptop}}}
+		if err := remapOutputs(&snap, outputs, map[string]string{"eDP-1": target}); err == nil {
+			t.Errorf("mapping to %s, which is not connected, succeeded", target)
+		}
+	}
+}
+
+func TestOutputFingerprint(t *testing.T) {
+	a := i3.Output{Name: "DP-1", Active: true, Rect: i3.Rect{Width: 1920, Height: 1080}}
+	b := i3.Output{Name: "eDP-1", Active: true, Rect: i3.Rect{X: 1920, Width: 1920, Height: 1080}}
+	off := i3.Output{Name: "HDMI-1"}
+	moved := b
+	moved.Rect.X = 3840
+
+	if outputFingerprint([]i3.Output{a, b}) != outputFingerprint([]i3.Output{b, off, a}) {
+		t.Error("fingerprint depends on the order or on disabled outputs")
+	}
+	if outputFingerprint([]i3.Output{a, b}) == outputFingerprint([]i3.Output{a, moved}) {
+		t.Error("fingerprint ignores the output geometry")
+	}
+	if got := outputFingerprint([]i3.Output{off}); got != "" {
+		t.Errorf("fingerprint without active outputs = %q, want empty", got)
+	}
+}
@@ -0,0 +1,376 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"go.i3wm.org/i3"
+)
+
+// windowTimeout is how long a restore waits for relaunched windows to show up.
+const windowTimeout = 10 * time.Second
+
+// Plan is everything a restore does, as an ordered list of steps. It is built from a
+// snapshot and the live tree without side effects, so the same plan can be printed for a
+// dry run, reported on while it runs and checked in tests; an Executor performs it.
+type Plan struct {
+	Steps []Step
+
+	// Existing holds the containers of the windows that were open when the plan was built.
+	// They are never taken for a relaunched window.
+	Existing []i3.NodeID
+}
+
+// Step is a single action of a Plan. Describe renders it the way a dry run prints it.
+type Step interface {
+	Describe() string
+}
+
+// WindowTarget identifies a saved window. Steps acting on a window act on the container the
+// window was matched to by an earlier AwaitWindows step, and do nothing if it never showed up.
+type WindowTarget struct {
+	NodeID   int64
+	Class    string
+	Instance string
+}
+
+// criteria stands in for the i3 criteria of the container, which is only known once the
+// window has appeared.
+func (t WindowTarget) criteria() string {
+	return fmt.Sprintf("[con_id=<window %s/%s>]", t.Class, t.Instance)
+}
+
+// SwitchWorkspace switches to a workspace and, if Output is set, moves it to that output.
+type SwitchWorkspace struct {
+	Name   string
+	Output string
+}
+
+// AppendLayout loads a saved layout into a workspace, creating the placeholders the
+// relaunched windows are swallowed into.
+type AppendLayout struct {
+	Workspace string
+	Layout    models.I3LayoutNode
+}
+
+// Launch starts a process that recreates a saved window. Env is applied on top of the
+// environment of i3-snapshot itself.
+type Launch struct {
+	Window WindowTarget
+	Argv   []string
+	Dir    string
+	Env    map[string]string
+}
+
+// AwaitWindows waits until a new window has appeared for each of Windows, or Timeout has
+// passed. Windows are matched by class and instance, anywhere in the tree.
+type AwaitWindows struct {
+	Workspace string // workspace the windows are meant for, empty for the scratchpad
+	Windows   []WindowTarget
+	Timeout   time.Duration
+}
+
+// MoveWindow moves a window to Workspace if it appeared somewhere else, or to the
+// scratchpad if Scratchpad is set.
+type MoveWindow struct {
+	Window     WindowTarget
+	Workspace  string
+	Scratchpad bool
+}
+
+// KillPlaceholder closes every placeholder on Workspace that no window was swallowed into.
+type KillPlaceholder struct {
+	Workspace string
+}
+
+// ApplyGeometry floats a window at its saved rect, positioned relative to Output if set.
+type ApplyGeometry struct {
+	Window WindowTarget
+	Rect   models.Rect
+	Output *models.OutputRef
+}
+
+// ApplyState reapplies the saved floating, marks, title format, sticky and fullscreen state of a window.
+type ApplyState struct {
+	Window WindowTarget
+	State  models.LayoutNode
+}
+
+// FocusWindow focuses a window.
+type FocusWindow struct {
+	Window WindowTarget
+}
+
+// BuildPlan works out how to restore snap. outputs are the connected outputs the saved ones
+// were mapped onto (see remapOutputs) and tree is the live tree before the restore.
+// Per workspace the plan switches to it, appends its layout, launches its windows, waits
+// for them, gives them their saved place and state back and focuses them in saved order.
+// The scratchpad comes next, and finally the visible and focused workspaces are shown again.
+func BuildPlan(snap models.Snapshot, tree i3.Tree, outputs []i3.Output) Plan {
+	var plan Plan
+	for _, n := range collectWindows(tree.Root) {
+		plan.Existing = append(plan.Existing, n.ID)
+	}
+
+	add := func(steps ...Step) {
+		plan.Steps = append(plan.Steps, steps...)
+	}
+
+	for _, ws := range snap.Workspaces {
+		if skipWorkspace(ws.Name) {
+			continue
+		}
+
+		add(SwitchWorkspace{Name: ws.Name, Output: resolveOutput(ws.Output, outputs)})
+
+		layoutRoot := workspaceLayout(ws)
+		if layoutRoot == nil {
+			// empty workspace, skip layout but still launch windows for this workspace
+			add(launchSteps(ws.Windows)...)
+			continue
+		}
+		add(AppendLayout{Workspace: ws.Name, Layout: convertToI3Layout(layoutRoot)})
+
+		// launch commands for THIS workspace while we're still on it
+		// this ensures windows open in the correct workspace
+		launches := launchSteps(ws.Windows)
+		if len(launches) == 0 {
+			continue
+		}
+		add(launches...)
+
+		targets := windowTargets(launches)
+		add(AwaitWindows{Workspace: ws.Name, Windows: targets, Timeout: windowTimeout})
+
+		launched := make(map[int64]bool, len(targets))
+		for _, t := range targets {
+			launched[t.NodeID] = true
+			add(MoveWindow{Window: t, Workspace: ws.Name})
+		}
+		add(placementSteps(&ws.Root, launched, ws.Output)...)
+		add(KillPlaceholder{Workspace: ws.Name})
+
+		// focus windows least recent first, so every container ends up with its saved
+		// focus stack (e.g. the same tab on top in tabbed containers)
+		saved := windowNodes(&ws.Root)
+		for _, id := range focusOrder(&ws.Root) {
+			if launched[id] {
+				add(FocusWindow{Window: windowTarget(saved[id])})
+			}
+		}
+	}
+
+	// scratchpad windows go last, they are launched on the current workspace and then hidden
+	if sp := snap.Scratchpad; sp != nil {
+		if launches := launchSteps(sp.Windows); len(launches) > 0 {
+			add(launches...)
+
+			targets := windowTargets(launches)
+			add(AwaitWindows{Windows: targets, Timeout: windowTimeout})
+
+			launched := make(map[int64]bool, len(targets))
+			for _, t := range targets {
+				launched[t.NodeID] = true
+			}
+			// floated at their saved geometry first, so "scratchpad show" brings them back
+			// at the same position and size as before; the rects were remapped onto the
+			// connected outputs and are absolute, as the windows open wherever the focus is
+			add(placementSteps(&sp.Root, launched, nil)...)
+			for _, t := range targets {
+				add(MoveWindow{Window: t, Scratchpad: true})
+			}
+		}
+	}
+
+	// finally show the workspaces that were visible and focus what was focused
+	var focused *models.WorkspaceSnapshot
+	for i := range snap.Workspaces {
+		ws := &snap.Workspaces[i]
+		if ws.Focused {
+			focused = ws
+		} else if ws.Visible {
+			add(SwitchWorkspace{Name: ws.Name})
+		}
+	}
+	if focused != nil {
+		add(SwitchWorkspace{Name: focused.Name})
+		if id, ok := focusedNode(&focused.Root); ok {
+			if node, ok := windowNodes(&focused.Root)[id]; ok && launchedWindow(focused.Windows, id) {
+				add(FocusWindow{Window: windowTarget(node)})
+			}
+		}
+	}
+
+	return plan
+}
+
+// launchSteps returns a Launch for every window that has a command to relaunch it with.
+// Windows of clients on another machine are left out: their WM_COMMAND would start the
+// application here instead of on that machine.
+func launchSteps(windows []models.WindowRef) []Step {
+	var steps []Step
+	for _, w := range windows {
+		argv := launchArgv(w)
+		if len(argv) == 0 || w.Machine != "" {
+			continue
+		}
+
+		dir := w.Cwd
+		// terminals start their shell in their own cwd, so start them where the shell was
+		if w.Shell != nil && w.Shell.Cwd != "" {
+			dir = w.Shell.Cwd
+		}
+
+		steps = append(steps, Launch{
+			Window: WindowTarget{NodeID: w.NodeID, Class: w.Class, Instance: w.Instance},
+			Argv:   argv,
+			Dir:    dir,
+			Env:    w.Env,
+		})
+	}
+	return steps
+}
+
+// windowTargets returns the windows started by launches.
+func windowTargets(launches []Step) []WindowTarget {
+	targets := make([]WindowTarget, 0, len(launches))
+	for _, s := range launches {
+		targets = append(targets, s.(Launch).Window)
+	}
+	return targets
+}
+
+// placementSteps gives every launched window below root its saved floating geometry and
+// container state back.
+func placementSteps(root *models.LayoutNode, launched map[int64]bool, output *models.OutputRef) []Step {
+	var steps []Step
+	floating := floatingRects(root)
+	for _, n := range windowList(root) {
+		if !launched[n.ID] {
+			continue
+		}
+		t := windowTarget(n)
+		if r, ok := floating[n.ID]; ok {
+			steps = append(steps, ApplyGeometry{Window: t, Rect: r, Output: output})
+		}
+		if stateActions(n) != "" {
+			steps = append(steps, ApplyState{Window: t, State: models.LayoutNode{
+				Floating:    n.Floating,
+				Marks:       n.Marks,
+				TitleFormat: n.TitleFormat,
+				Sticky:      n.Sticky,
+				Fullscreen:  n.Fullscreen,
+			}})
+		}
+	}
+	return steps
+}
+
+// windowTarget identifies the window held by a saved layout node.
+func windowTarget(n *models.LayoutNode) WindowTarget {
+	return WindowTarget{NodeID: n.ID, Class: n.WindowClass, Instance: n.WindowInst}
+}
+
+// launchedWindow reports whether the window with saved node ID id gets relaunched.
+func launchedWindow(windows []models.WindowRef, id int64) bool {
+	for _, w := range windows {
+		if w.NodeID == id {
+			return len(launchArgv(w)) > 0 && w.Machine == ""
+		}
+	}
+	return false
+}
+
+// Describe renders the workspace switch and, if any, the move to its output.
+func (s SwitchWorkspace) Describe() string {
+	d := fmt.Sprintf("i3:     workspace %s", s.Name)
+	if s.Output != "" {
+		d += fmt.Sprintf("\ni3:     move workspace to output %s", s.Output)
+	}
+	return d
+}
+
+// Describe renders the append_layout command with the indented layout it loads.
+func (s AppendLayout) Describe() string {
+	data, err := layoutJSON(s.Layout)
+	if err != nil {
+		return fmt.Sprintf("i3:     append_layout <file> (%v)", err)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "        ", "  "); err != nil {
+		buf.Reset()
+		buf.Write(data)
+	}
+	return "i3:     append_layout <file>\n        " + buf.String()
+}
+
+// Describe renders the command line with its working directory and environment.
+func (s Launch) Describe() string {
+	d := "launch: " + quoteArgv(s.Argv)
+	if s.Dir != "" {
+		d += "\n        cwd: " + s.Dir
+	}
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		d += fmt.Sprintf("\n        env: %s=%s", k, s.Env[k])
+	}
+	return d
+}
+
+// Describe renders how long and for how many windows the restore waits.
+func (s AwaitWindows) Describe() string {
+	if s.Workspace == "" {
+		return fmt.Sprintf("wait:   up to %s for %d new scratchpad windows", s.Timeout, len(s.Windows))
+	}
+	return fmt.Sprintf("wait:   up to %s for %d new windows for workspace %s", s.Timeout, len(s.Windows), s.Workspace)
+}
+
+// Describe renders the move to the workspace or to the scratchpad.
+func (s MoveWindow) Describe() string {
+	if s.Scratchpad {
+		return fmt.Sprintf("i3:     %s move scratchpad", s.Window.criteria())
+	}
+	return fmt.Sprintf("i3:     %s move container to workspace %s (if it opened elsewhere)", s.Window.criteria(), s.Workspace)
+}
+
+// Describe renders the kill of the placeholders left on the workspace.
+func (s KillPlaceholder) Describe() string {
+	return fmt.Sprintf("i3:     [con_id=<placeholder>] kill (every placeholder left on workspace %s)", s.Workspace)
+}
+
+// Describe renders the floating geometry commands for the window.
+func (s ApplyGeometry) Describe() string {
+	return fmt.Sprintf("i3:     %s %s", s.Window.criteria(), floatingActions(s.Rect, s.Output))
+}
+
+// Describe renders the container state commands for the window.
+func (s ApplyState) Describe() string {
+	return fmt.Sprintf("i3:     %s %s", s.Window.criteria(), stateActions(&s.State))
+}
+
+// Describe renders the focus command for the window.
+func (s FocusWindow) Describe() string {
+	return fmt.Sprintf("i3:     %s focus", s.Window.criteria())
+}
+
+// quoteArgv renders argv as a shell-like command line, quoting arguments where needed.
+func quoteArgv(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`;&|<>()*?[]#~!{}") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,222 @@
+package snapshot
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"go.i3wm.org/i3"
+)
+
+// stepString renders a plan step compactly for comparison.
+func stepString(step Step) string {
+	switch s := step.(type) {
+	case SwitchWorkspace:
+		if s.Output != "" {
+			return fmt.Sprintf("workspace %s on %s", s.Name, s.Output)
+		}
+		return "workspace " + s.Name
+	case AppendLayout:
+		return "layout " + s.Workspace
+	case Launch:
+		return "launch " + strings.Join(s.Argv, " ")
+	case AwaitWindows:
+		ids := make([]string, len(s.Windows))
+		for i, w := range s.Windows {
+			ids[i] = fmt.Sprint(w.NodeID)
+		}
+		return fmt.Sprintf("await %q: %s", s.Workspace, strings.Join(ids, " "))
+	case MoveWindow:
+		if s.Scratchpad {
+			return fmt.Sprintf("scratchpad %d", s.Window.NodeID)
+		}
+		return fmt.Sprintf("move %d to %s", s.Window.NodeID, s.Workspace)
+	case KillPlaceholder:
+		return "kill placeholders on " + s.Workspace
+	case ApplyGeometry:
+		return fmt.Sprintf("geometry %d %dx%d+%d+%d", s.Window.NodeID, s.Rect.Width, s.Rect.Height, s.Rect.X, s.Rect.Y)
+	case ApplyState:
+		return fmt.Sprintf("state %d: %s", s.Window.NodeID, stateActions(&s.State))
+	case FocusWindow:
+		return fmt.Sprintf("focus %d", s.Window.NodeID)
+	}
+	return fmt.Sprintf("%T", step)
+}
+
+// window returns a saved window container.
+func window(id int64, class, instance string) models.LayoutNode {
+	return models.LayoutNode{ID: id, Type: "con", WindowID: int(id) << 8, WindowClass: class, WindowInst: instance}
+}
+
+// floating wraps a saved window in a floating container at rect.
+func floating(id int64, rect models.Rect, win models.LayoutNode) models.LayoutNode {
+	win.Floating = "user_on"
+	return models.LayoutNode{ID: id, Type: "floating_con", Rect: rect, Nodes: []models.LayoutNode{win}}
+}
+
+func TestBuildPlan(t *testing.T) {
+	dp1 := &models.OutputRef{Name: "DP-1", Rect: models.Rect{Width: 1920, Height: 1080}}
+	connected := []i3.Output{
+		{Name: "DP-1", Active: true},
+		{Name: "eDP-1", Active: true, Primary: true},
+	}
+
+	term := window(11, "Alacritty", "Alacritty")
+	web := window(12, "firefox", "Navigator")
+	web.Marks = []string{"web"}
+	web.Focused = true
+	tiled := models.WorkspaceSnapshot{
+		Name:    "1",
+		Output:  dp1,
+		Focused: true,
+		Root: models.LayoutNode{
+			Type: "workspace", Layout: "splith",
+			Focus: []int64{12, 11},
+			Nodes: []models.LayoutNode{term, web},
+		},
+		Windows: []models.WindowRef{
+			{NodeID: 11, Class: "Alacritty", Instance: "Alacritty", Argv: []string{"alacritty"}},
+			{NodeID: 12, Class: "firefox", Instance: "Navigator", Argv: []string{"firefox"}},
+		},
+	}
+
+	rect := models.Rect{X: 100, Y: 100, Width: 800, Height: 600}
+
+	tests := []struct {
+		name    string
+		snap    models.Snapshot
+		outputs []i3.Output
+		want    []string
+	}{
+		{
+			name:    "tiled windows",
+			snap:    models.Snapshot{Workspaces: []models.WorkspaceSnapshot{tiled}},
+			outputs: connected,
+			want: []string{
+				"workspace 1 on DP-1",
+				"layout 1",
+				"launch alacritty",
+				"launch firefox",
+				`await "1": 11 12`,
+				"move 11 to 1",
+				"move 12 to 1",
+				`state 12: mark --add "web"`,
+				"kill placeholders on 1",
+				"focus 11", // least recently focused first
+				"focus 12",
+				"workspace 1",
+				"focus 12",
+			},
+		},
+		{
+			name: "window without command",
+			snap: func() models.Snapshot {
+				ws := tiled
+				ws.Windows = []models.WindowRef{ws.Windows[0], {NodeID: 12, Class: "firefox"}}
+				return models.Snapshot{Workspaces: []models.WorkspaceSnapshot{ws}}
+			}(),
+			outputs: connected,
+			want: []string{
+				"workspace 1 on DP-1",
+				"layout 1",
+				"launch alacritty",
+				`await "1": 11`,
+				"move 11 to 1",
+				"kill placeholders on 1",
+				"focus 11",
+				"workspace 1",
+			},
+		},
+		{
+			name: "empty and internal workspaces",
+			snap: models.Snapshot{Workspaces: []models.WorkspaceSnapshot{
+				{Name: "__i3_scratch", Root: models.LayoutNode{Type: "workspace"}},
+				{Name: "2", Visible: true, Root: models.LayoutNode{Type: "workspace"}},
+			}},
+			outputs: connected,
+			want:    []string{"workspace 2", "workspace 2"},
+		},
+		{
+			name: "output gone",
+			snap: models.Snapshot{Workspaces: []models.WorkspaceSnapshot{
+				{Name: "3", Output: &models.OutputRef{Name: "HDMI-1"}, Root: models.LayoutNode{Type: "workspace"}},
+				{Name: "4", Output: &models.OutputRef{Name: "DP-9", Primary: true}, Root: models.LayoutNode{Type: "workspace"}},
+			}},
+			outputs: connected,
+			want:    []string{"workspace 3", "workspace 4 on eDP-1"},
+		},
+		{
+			name: "floating window",
+			snap: models.Snapshot{Workspaces: []models.WorkspaceSnapshot{{
+				Name:   "5",
+				Output: dp1,
+				Root: models.LayoutNode{
+					Type:          "workspace",
+					FloatingNodes: []models.LayoutNode{floating(20, rect, window(21, "mpv", "gl"))},
+				},
+				Windows: []models.WindowRef{{NodeID: 21, Class: "mpv", Instance: "gl", Argv: []string{"mpv", "video.mkv"}}},
+			}}},
+			outputs: connected,
+			want: []string{
+				"workspace 5 on DP-1",
+				"layout 5",
+				"launch mpv video.mkv",
+				`await "5": 21`,
+				"move 21 to 5",
+				"geometry 21 800x600+100+100",
+				"state 21: floating enable",
+				"kill placeholders on 5",
+			},
+		},
+		{
+			name: "scratchpad",
+			snap: models.Snapshot{Scratchpad: &models.ScratchpadSnapshot{
+				Root: models.LayoutNode{
+					Type: "workspace", Name: "__i3_scratch",
+					FloatingNodes: []models.LayoutNode{floating(30, rect, window(31, "KeePassXC", "keepassxc"))},
+				},
+				Windows: []models.WindowRef{{NodeID: 31, Class: "KeePassXC", Argv: []string{"keepassxc"}}},
+			}},
+			want: []string{
+				"launch keepassxc",
+				`await "": 31`,
+				"geometry 31 800x600+100+100",
+				"state 31: floating enable",
+				"scratchpad 31",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := BuildPlan(tt.snap, i3.Tree{}, tt.outputs)
+			var got []string
+			for _, step := range plan.Steps {
+				got = append(got, stepString(step))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("BuildPlan() steps:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(tt.want, "\n  "))
+			}
+		})
+	}
+}
+
+func TestBuildPlanExisting(t *testing.T) {
+	tree := i3.Tree{Root: &i3.Node{ID: 1, Type: i3.Root, Nodes: []*i3.Node{
+		{ID: 2, Type: i3.OutputNode, Nodes: []*i3.Node{
+			{ID: 3, Type: i3.WorkspaceNode, Name: "1", Nodes: []*i3.Node{
+				{ID: 4, Type: i3.Con, Window: 0x100},
+				{ID: 5, Type: i3.Con},
+			}},
+		}},
+	}}}
+
+	plan := BuildPlan(models.Snapshot{}, tree, nil)
+	if want := []i3.NodeID{4}; !slices.Equal(plan.Existing, want) {
+		t.Errorf("Existing = %v, want %v", plan.Existing, want)
+	}
+	if len(plan.Steps) != 0 {
+		t.Errorf("empty snapshot gives %d steps", len(plan.Steps))
+	}
+}
@@ -3,150 +3,189 @@ package snapshot
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
-	"sync"
-	"time"
 
+	i3internal "github.com/a9sk/i3-snapshot/internal/i3"
 	"github.com/a9sk/i3-snapshot/internal/models"
+	"github.com/a9sk/i3-snapshot/internal/store"
 	"go.i3wm.org/i3"
 )
 
-var (
-	// getTree is a helper to access i3.GetTree from this package
-	getTree = func() (i3.Tree, error) {
-		return i3.GetTree()
+// RestoreOptions tweaks how a snapshot is restored.
+type RestoreOptions struct {
+	// Rewrite saves the snapshot back to disk after it was migrated from an
+	// older schema version, so the migration only has to run once.
+	Rewrite bool
+
+	// OutputMap forces saved outputs onto specific connected outputs (saved name -> target name).
+	// Saved outputs not listed here are mapped automatically.
+	OutputMap map[string]string
+
+	// Workspaces restores only the saved workspaces with these names, without the scratchpad.
+	Workspaces []string
+	// Rename restores saved workspaces under other names (saved name -> new name).
+	Rename map[string]string
+	// Offset is added to the number of every numbered workspace not listed in Rename.
+	Offset int
+
+	// DryRun prints every i3 command and process launch the restore would perform to Out
+	// instead of performing them. The connected outputs and open windows are read from i3
+	// if it is running; otherwise the plan assumes the saved outputs and no open windows.
+	DryRun bool
+	Out    io.Writer
+
+	// Progress, if set, is called before each step of the restore plan is run.
+	Progress func(done, total int, step Step)
+
+	// Client is the i3 to restore into; nil means the running i3.
+	Client i3internal.Client
+	// Start starts the processes the restore launches; nil means (*exec.Cmd).Start.
+	Start func(cmd *exec.Cmd) error
+}
+
+// client returns the i3 client to use.
+func (o RestoreOptions) client() i3internal.Client {
+	if o.Client != nil {
+		return o.Client
 	}
-)
+	return i3internal.DefaultClient()
+}
 
 // Restore replays a previously saved snapshot by name.
 // It:
-//  1. loads ~/.config/i3-snapshot/saves/<name>.json
-//  2. for each workspace: switches to it, applies layout, then launches commands
-//  3. launches all recorded commands concurrently
-func Restore(name string) error {
-	snap, err := loadSnapshot(name)
+//  1. loads the snapshot from the store, migrating older schema versions, then picks and
+//     renames the workspaces to restore
+//  2. maps saved outputs onto the connected ones, scaling the saved geometry
+//  3. builds the restore plan (see BuildPlan) from the snapshot and the live tree
+//  4. prints the plan for a dry run, or runs it against i3
+func Restore(st store.SnapshotStore, name string, opts RestoreOptions) error {
+	snap, migrated, err := loadSnapshot(st, name)
 	if err != nil {
 		return err
 	}
 
-	// restore each workspace
-	for _, ws := range snap.Workspaces {
-		// skip invalid or internal i3 workspaces
-		if ws.Name == "" || ws.Name == "root" || strings.HasPrefix(ws.Name, "__i3_") {
-			continue
+	if migrated && opts.Rewrite && !opts.DryRun {
+		if err := st.Put(name, snap); err != nil {
+			return fmt.Errorf("rewriting migrated snapshot %s: %w", name, err)
 		}
+	}
 
-		// switch to the workspace
-		cmd := fmt.Sprintf("workspace %s", ws.Name)
-		if _, err := i3.RunCommand(cmd); err != nil {
-			return fmt.Errorf("switching to workspace %s: %w", ws.Name, err)
-		}
+	// only after a possible rewrite: the stored snapshot must stay complete
+	if err := selectWorkspacesToRestore(&snap, opts); err != nil {
+		return err
+	}
 
-		time.Sleep(200 * time.Millisecond)
+	client := opts.client()
+	out := opts.Out
+	if out == nil {
+		out = os.Stdout
+	}
 
-		// extract workspace children for append_layout
-		var layoutRoot *models.LayoutNode
-		if ws.Root.Type == "workspace" {
-			if len(ws.Root.Nodes) == 0 && len(ws.Root.FloatingNodes) == 0 {
-				// empty workspace, skip layout but still launch windows for this workspace
-				launchCommands(ws.Windows)
-				continue
-			}
+	// outputs connected right now, used to place workspaces on their saved monitor
+	outputs, err := client.GetOutputs()
+	if err != nil && !opts.DryRun {
+		return fmt.Errorf("getting outputs: %w", err)
+	}
+	online := err == nil
+	if !online {
+		// a dry run works without i3: plan as if the saved outputs were connected and
+		// no window was open
+		fmt.Fprintf(out, "note:   i3 is not reachable (%v), planning for the saved outputs and no open windows\n", err)
+		outputs = snapshotOutputs(&snap)
+	}
 
-			layoutRoot = &models.LayoutNode{
-				Type:          "con",
-				Layout:        ws.Root.Layout,
-				Nodes:         ws.Root.Nodes,
-				FloatingNodes: ws.Root.FloatingNodes,
-				Rect:          ws.Root.Rect,
-			}
-		} else {
-			layoutRoot = &ws.Root
-		}
+	// the monitors may have changed since the snapshot was taken (different dock,
+	// laptop panel only, ...): move every workspace onto a connected output and
+	// scale its geometry before any layout is applied
+	if err := remapOutputs(&snap, outputs, opts.OutputMap); err != nil {
+		return err
+	}
 
-		// apply layout to this workspace
-		if err := applyLayout(layoutRoot); err != nil {
-			return fmt.Errorf("applying layout to workspace %s: %w", ws.Name, err)
+	// the windows that are already open must not be mistaken for relaunched ones
+	var tree i3.Tree
+	if online {
+		if tree, err = client.GetTree(); err != nil {
+			return err
 		}
+	}
 
-		// wait a bit for layout placeholders to be created
-		time.Sleep(200 * time.Millisecond)
-
-		// launch commands for THIS workspace while we're still on it
-		// this ensures windows open in the correct workspace
-		launchCommands(ws.Windows)
+	plan := BuildPlan(snap, tree, outputs)
 
-		// wait for windows to appear and get swallowed by placeholders
-		// this is important for slow-starting apps like browsers
-		waitForWindows(ws.Name, ws.Windows, 10*time.Second)
+	if opts.DryRun {
+		return printPlan(out, plan)
 	}
 
-	return nil
+	e := &Executor{I3: client, Start: opts.Start, Progress: opts.Progress}
+	return e.Execute(plan)
 }
 
-// loadSnapshot loads a snapshot JSON by name from the config directory.
-func loadSnapshot(name string) (models.Snapshot, error) {
-	configDir, err := os.UserConfigDir()
-	if err != nil {
-		return models.Snapshot{}, fmt.Errorf("resolving config dir: %w", err)
-	}
-	path := filepath.Join(configDir, "i3-snapshot", "saves", name+".json")
+// skipWorkspace reports whether a saved workspace is invalid or internal to i3 and
+// must not be restored.
+func skipWorkspace(name string) bool {
+	return name == "" || name == "root" || strings.HasPrefix(name, "__i3_")
+}
 
-	f, err := os.Open(path)
+// loadSnapshot loads a snapshot by name from the store and upgrades it to the current
+// schema version. The returned bool reports whether a migration ran.
+func loadSnapshot(st store.SnapshotStore, name string) (models.Snapshot, bool, error) {
+	snap, err := st.Get(name)
 	if err != nil {
-		return models.Snapshot{}, fmt.Errorf("opening snapshot %s: %w", path, err)
+		return models.Snapshot{}, false, err
 	}
-	defer f.Close()
 
-	var snap models.Snapshot
-	if err := json.NewDecoder(f).Decode(&snap); err != nil {
-		return models.Snapshot{}, fmt.Errorf("decoding snapshot %s: %w", path, err)
+	migrated, err := migrateSnapshot(&snap)
+	if err != nil {
+		return models.Snapshot{}, false, fmt.Errorf("loading snapshot %s: %w", name, err)
 	}
-	return snap, nil
+	return snap, migrated, nil
 }
 
-// applyLayout converts our LayoutNode to i3's expected format and applies it.
-func applyLayout(root *models.LayoutNode) error {
-	i3Root := convertToI3Layout(root)
+// Load returns the snapshot called name, migrated to the current schema version.
+func Load(st store.SnapshotStore, name string) (models.Snapshot, error) {
+	snap, _, err := loadSnapshot(st, name)
+	return snap, err
+}
 
-	data, err := json.Marshal(i3Root)
-	if err != nil {
-		return fmt.Errorf("marshalling layout: %w", err)
+// workspaceLayout returns the node whose layout append_layout recreates on a workspace,
+// or nil if the workspace is empty.
+func workspaceLayout(ws models.WorkspaceSnapshot) *models.LayoutNode {
+	if ws.Root.Type != "workspace" {
+		return &ws.Root
 	}
-
-	// i3 expects a file for append_layout, so we write to a temp file and clean it up
-	tmp, err := os.CreateTemp("", "i3-snapshot-layout-*.json")
-	if err != nil {
-		return fmt.Errorf("creating temp layout file: %w", err)
+	if len(ws.Root.Nodes) == 0 && len(ws.Root.FloatingNodes) == 0 {
+		return nil
 	}
-	defer os.Remove(tmp.Name())
-
-	if _, err := tmp.Write(data); err != nil {
-		tmp.Close()
-		return fmt.Errorf("writing temp layout file: %w", err)
+	// extract workspace children for append_layout
+	return &models.LayoutNode{
+		Type:          "con",
+		Layout:        ws.Root.Layout,
+		Nodes:         ws.Root.Nodes,
+		FloatingNodes: ws.Root.FloatingNodes,
+		Rect:          ws.Root.Rect,
 	}
-	tmp.Close()
+}
 
-	reply, err := i3.RunCommand(fmt.Sprintf("append_layout %s", tmp.Name()))
+// layoutJSON encodes a layout the way append_layout expects it.
+func layoutJSON(layout models.I3LayoutNode) ([]byte, error) {
+	data, err := json.Marshal(layout)
 	if err != nil {
-		return fmt.Errorf("running append_layout: %w", err)
+		return nil, fmt.Errorf("marshalling layout: %w", err)
 	}
-	_ = reply // TODO: in future we might inspect success/failure per command
-
-	return nil
+	return data, nil
 }
 
 // convertToI3Layout converts our LayoutNode to i3's expected format with swallows.
 // It filters out invalid windows (like Cursor, i3bar, etc.) that shouldn't be restored.
 func convertToI3Layout(n *models.LayoutNode) models.I3LayoutNode {
 	node := models.I3LayoutNode{
-		Type:   n.Type,
-		Layout: n.Layout,
-		Border: n.Border,
-		Rect:   n.Rect,
+		Type:    n.Type,
+		Layout:  n.Layout,
+		Border:  n.Border,
+		Rect:    n.Rect,
+		Percent: n.Percent,
 	}
 
 	// only include ID and Name for non-workspace containers to avoid creating workspaces
@@ -188,309 +227,127 @@ func convertToI3Layout(n *models.LayoutNode) models.I3LayoutNode {
 	return node
 }
 
-// waitForWindows waits for windows to appear and get swallowed by placeholders.
-// It checks the i3 tree periodically to see if windows matching th criteria
-// have appeared in the correct workspace. Returns after timeout or when all windows are found.
-func waitForWindows(workspaceName string, expectedWindows []models.WindowRef, timeout time.Duration) {
-	if len(expectedWindows) == 0 {
-		return
+// windowNodes maps the node ID of every window in the saved layout to its LayoutNode.
+func windowNodes(root *models.LayoutNode) map[int64]*models.LayoutNode {
+	nodes := make(map[int64]*models.LayoutNode)
+	for _, n := range windowList(root) {
+		nodes[n.ID] = n
 	}
+	return nodes
+}
 
-	deadline := time.Now().Add(timeout)
-	checkInterval := 200 * time.Millisecond
-
-	for time.Now().Before(deadline) {
-		tree, err := getTree()
-		if err != nil {
-			time.Sleep(checkInterval)
-			continue
-		}
-
-		// find the workspace in the tree
-		var workspaceNode *i3.Node
-		var findWorkspace func(n *i3.Node)
-		findWorkspace = func(n *i3.Node) {
-			if n == nil || workspaceNode != nil {
-				return
-			}
-			if n.Type == i3.WorkspaceNode && n.Name == workspaceName {
-				workspaceNode = n
-				return
-			}
-			for i := range n.Nodes {
-				findWorkspace(n.Nodes[i])
-			}
-		}
-		findWorkspace(tree.Root)
-
-		if workspaceNode == nil {
-			time.Sleep(checkInterval)
-			continue
-		}
-
-		// count how many expected windows we've found in this workspace
-		// and also check for windows in other workspaces that should be moved here
-		foundCount := 0
-		var windowsToMove []*i3.Node
+// windowList returns every window in the saved layout in tree order, tiling before floating.
+func windowList(root *models.LayoutNode) []*models.LayoutNode {
+	var nodes []*models.LayoutNode
 
-		var checkWindow func(n *i3.Node, inTargetWorkspace bool)
-		checkWindow = func(n *i3.Node, inTargetWorkspace bool) {
-			if n == nil {
-				return
-			}
-			if n.Window != 0 {
-				wp := n.WindowProperties
-				// check if this window matches any of our expected windows
-				for _, expected := range expectedWindows {
-					if expected.Command == "" {
-						continue // skip windows without commands
-					}
-					// match by class and instance (title can change)
-					if (expected.Class == "" || wp.Class == expected.Class) &&
-						(expected.Instance == "" || wp.Instance == expected.Instance) {
-						if inTargetWorkspace {
-							foundCount++
-						} else {
-							// window is in wrong workspace, mark it for moving
-							windowsToMove = append(windowsToMove, n)
-						}
-						break
-					}
-				}
-			}
-			for i := range n.Nodes {
-				checkWindow(n.Nodes[i], inTargetWorkspace)
-			}
-			for i := range n.FloatingNodes {
-				checkWindow(n.FloatingNodes[i], inTargetWorkspace)
-			}
+	var collect func(n *models.LayoutNode)
+	collect = func(n *models.LayoutNode) {
+		if n.WindowID != 0 {
+			nodes = append(nodes, n)
 		}
-
-		// check the target workspace
-		checkWindow(workspaceNode, true)
-
-		// also check all other workspaces for windows that should be here
-		var checkAllWorkspaces func(n *i3.Node)
-		checkAllWorkspaces = func(n *i3.Node) {
-			if n == nil {
-				return
-			}
-			if n.Type == i3.WorkspaceNode && n.Name != workspaceName {
-				checkWindow(n, false)
-			}
-			for i := range n.Nodes {
-				checkAllWorkspaces(n.Nodes[i])
-			}
+		for i := range n.Nodes {
+			collect(&n.Nodes[i])
 		}
-		checkAllWorkspaces(tree.Root)
-
-		// move windows that appeared in wrong workspace
-		for _, win := range windowsToMove {
-			cmd := fmt.Sprintf("[id=\"%d\"] move workspace %s", win.Window, workspaceName)
-			i3.RunCommand(cmd)
+		for i := range n.FloatingNodes {
+			collect(&n.FloatingNodes[i])
 		}
+	}
+	collect(root)
 
-		// if we found all windows (or most of them), we're done
-		// we use "most" because some windows might not have commands saved
-		expectedCount := 0
-		for _, w := range expectedWindows {
-			if w.Command != "" {
-				expectedCount++
-			}
-		}
-		if foundCount >= expectedCount || foundCount >= len(expectedWindows) {
-			// give a bit more time for slow apps to fully initialize
-			time.Sleep(500 * time.Millisecond)
+	return nodes
+}
 
-			removePlaceholders(workspaceName, expectedWindows)
-			return
-		}
+// conCommand runs the i3 commands in actions on container conID.
+func conCommand(conID i3.NodeID, actions string) string {
+	return fmt.Sprintf("[con_id=\"%d\"] %s", conID, actions)
+}
 
-		time.Sleep(checkInterval)
+// stateCommand builds the i3 command that reapplies the saved container state of n
+// (floating, marks, title format, sticky, fullscreen) to container conID. Percent is not handled
+// here since it is part of the append_layout JSON. Returns "" if there is nothing to do.
+func stateCommand(conID i3.NodeID, n *models.LayoutNode) string {
+	actions := stateActions(n)
+	if actions == "" {
+		return ""
 	}
-
-	// timeout reached, try to clean up placeholders anyway
-	removePlaceholders(workspaceName, expectedWindows)
+	return conCommand(conID, actions)
 }
 
-// removePlaceholders removes placeholder windows that weren't swallowed by real windows.
-// Placeholders are containers with no actual window (Window == 0) that are waiting to be swallowed.
-// We identify them by checking if they have no window and no children with windows.
-func removePlaceholders(workspaceName string, expectedWindows []models.WindowRef) {
-	tree, err := getTree()
-	if err != nil {
-		return
+// stateActions lists the commands stateCommand runs on the container, or "" if there are none.
+func stateActions(n *models.LayoutNode) string {
+	var cmds []string
+	// floating first: sticky only applies to floating containers
+	switch n.Floating {
+	case "user_on", "auto_on":
+		cmds = append(cmds, "floating enable")
+	case "user_off":
+		// tiled on purpose, although i3 may float the window by default (dialogs)
+		cmds = append(cmds, "floating disable")
 	}
-
-	var workspaceNode *i3.Node
-	var findWorkspace func(n *i3.Node)
-	findWorkspace = func(n *i3.Node) {
-		if n == nil || workspaceNode != nil {
-			return
-		}
-		if n.Type == i3.WorkspaceNode && n.Name == workspaceName {
-			workspaceNode = n
-			return
-		}
-		for i := range n.Nodes {
-			findWorkspace(n.Nodes[i])
-		}
+	for _, m := range n.Marks {
+		cmds = append(cmds, fmt.Sprintf("mark --add %s", quoteArg(m)))
 	}
-	findWorkspace(tree.Root)
-
-	if workspaceNode == nil {
-		return
+	if n.TitleFormat != "" {
+		cmds = append(cmds, fmt.Sprintf("title_format %s", quoteArg(n.TitleFormat)))
+	}
+	if n.Sticky {
+		cmds = append(cmds, "sticky enable")
 	}
+	// fullscreen last, so the commands above do not run against a fullscreen container
+	switch n.Fullscreen {
+	case 1:
+		cmds = append(cmds, "fullscreen enable")
+	case 2:
+		cmds = append(cmds, "fullscreen enable global")
+	}
+
+	return strings.Join(cmds, ", ")
+}
+
+// quoteArg quotes a string argument for an i3 command.
+func quoteArg(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
 
-	// first, collect all real windows that have appeared in this workspace
-	realWindows := make(map[string]bool)
-	var collectRealWindows func(n *i3.Node)
-	collectRealWindows = func(n *i3.Node) {
-		if n == nil {
-			return
+// floatingRects maps the node ID of every window inside a floating container to the
+// saved geometry of that floating container.
+func floatingRects(root *models.LayoutNode) map[int64]models.Rect {
+	rects := make(map[int64]models.Rect)
+
+	var collect func(n *models.LayoutNode, floatingRect *models.Rect)
+	collect = func(n *models.LayoutNode, floatingRect *models.Rect) {
+		if n.Type == string(i3.FloatingCon) {
+			floatingRect = &n.Rect
 		}
-		if n.Window != 0 {
-			wp := n.WindowProperties
-			// create a key from class+instance to identify this window
-			key := fmt.Sprintf("%s|%s", wp.Class, wp.Instance)
-			realWindows[key] = true
+		if n.WindowID != 0 && floatingRect != nil {
+			rects[n.ID] = *floatingRect
 		}
 		for i := range n.Nodes {
-			collectRealWindows(n.Nodes[i])
+			collect(&n.Nodes[i], floatingRect)
 		}
 		for i := range n.FloatingNodes {
-			collectRealWindows(n.FloatingNodes[i])
+			collect(&n.FloatingNodes[i], floatingRect)
 		}
 	}
-	collectRealWindows(workspaceNode)
-
-	// now find placeholders: containers with Window == 0 that have no children with windows
-	// and match expected windows that have already appeared (so the placeholder is no longer needed)
-	var placeholders []*i3.Node
-	var findPlaceholders func(n *i3.Node) bool
-	findPlaceholders = func(n *i3.Node) bool {
-		if n == nil {
-			return false
-		}
-
-		// check if this node or any descendant has a real window
-		hasRealWindow := false
-		if n.Window != 0 {
-			hasRealWindow = true
-		}
-
-		// check children recursively
-		for i := range n.Nodes {
-			if findPlaceholders(n.Nodes[i]) {
-				hasRealWindow = true
-			}
-		}
-		for i := range n.FloatingNodes {
-			if findPlaceholders(n.FloatingNodes[i]) {
-				hasRealWindow = true
-			}
-		}
-
-		// if this is a leaf container (no children) with no window, it's likely a placeholder
-		// layout containers have children, placeholders are leaf nodes waiting to be swallowed
-		isLeaf := len(n.Nodes) == 0 && len(n.FloatingNodes) == 0
-		if !hasRealWindow && n.Window == 0 && n.Type == i3.Con && isLeaf {
-			// check if we have any expected windows for this workspace
-			// if we do, and this is a leaf with no window, it's almost certainly a placeholder
-			hasExpectedWindows := false
-			for _, expected := range expectedWindows {
-				if expected.Command != "" {
-					hasExpectedWindows = true
-					break
-				}
-			}
+	collect(root, nil)
 
-			if hasExpectedWindows {
-				// any leaf container with Window == 0 in a workspace with expected windows
-				// is a placeholder (layout containers have children, real windows have Window != 0)
-				placeholders = append(placeholders, n)
-				return false // don't recurse further, we're removing this
-			}
-		}
-
-		return hasRealWindow
-	}
-	findPlaceholders(workspaceNode)
-
-	// remove placeholders (in reverse order to avoid ID changes)
-	for i := len(placeholders) - 1; i >= 0; i-- {
-		placeholder := placeholders[i]
-		cmd := fmt.Sprintf("[con_id=\"%d\"] kill", placeholder.ID)
-		i3.RunCommand(cmd)
-		// small delay to let i3 process the kill
-	}
+	return rects
 }
 
-// launchCommands starts each window's command in its recorded working directory.
-// Launch errors are logged to stderr but do not abort the whole restore.
-func launchCommands(windows []models.WindowRef) {
-	var wg sync.WaitGroup
-
-	for _, w := range windows {
-		cmdline := w.Command
-		if cmdline == "" {
-			continue
-		}
-
-		wg.Add(1)
-		go func(w models.WindowRef) {
-			defer wg.Done()
-
-			// naive splitting on spaces for now; TODO:in future we may want a shell parser
-			args := splitCommandLine(w.Command)
-			if len(args) == 0 {
-				return
-			}
-
-			cmd := exec.Command(args[0], args[1:]...)
-			if w.Cwd != "" {
-				cmd.Dir = w.Cwd
-			}
-
-			// detach: we don't need stdout/stderr and don't wait for completion
-			_ = cmd.Start()
-		}(w)
-	}
-
-	// allow goroutines to be scheduled; we don't strictly need to wait,
-	// but waiting briefly avoids exiting before Start() is called
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
-
-	select {
-	case <-done:
-	case <-time.After(2 * time.Second):
-	}
+// floatingCommand builds the i3 command that floats container conID and gives it the saved rect.
+// Positions are made relative to the workspace's output so they land on the right monitor;
+// without a recorded output we fall back to absolute screen coordinates.
+func floatingCommand(conID i3.NodeID, r models.Rect, output *models.OutputRef) string {
+	return conCommand(conID, floatingActions(r, output))
 }
 
-// splitCommandLine is a simple, conservative splitter: it splits on spaces and
-// ignores quoting/escaping. This is good enough for many GUI apps (e.g. "alacritty",
-// "firefox", "code /path"), but can be improved in future versions.
-//
-//	TODO: improve this
-func splitCommandLine(cmd string) []string {
-	var out []string
-	cur := ""
-	for _, r := range cmd {
-		if r == ' ' {
-			if cur != "" {
-				out = append(out, cur)
-				cur = ""
-			}
-			continue
-		}
-		cur += string(r)
-	}
-	if cur != "" {
-		out = append(out, cur)
+// floatingActions lists the commands floatingCommand runs on the container.
+func floatingActions(r models.Rect, output *models.OutputRef) string {
+	move := fmt.Sprintf("move absolute position %d px %d px", r.X, r.Y)
+	if output != nil {
+		move = fmt.Sprintf("move position %d px %d px", r.X-output.Rect.X, r.Y-output.Rect.Y)
 	}
-	return out
+	return fmt.Sprintf("floating enable, resize set %d px %d px, %s", r.Width, r.Height, move)
 }
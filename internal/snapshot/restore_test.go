@@ -0,0 +1,87 @@
+package snapshot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	i3internal "github.com/a9sk/i3-snapshot/internal/i3"
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"github.com/a9sk/i3-snapshot/internal/store"
+	"go.i3wm.org/i3"
+)
+
+func TestStateActions(t *testing.T) {
+	tests := []struct {
+		name string
+		node models.LayoutNode
+		want string
+	}{
+		{"nothing saved", models.LayoutNode{}, ""},
+		{"tiled by default", models.LayoutNode{Floating: "auto_off"}, ""},
+		{"floated by the user", models.LayoutNode{Floating: "user_on"}, "floating enable"},
+		{"floated by i3", models.LayoutNode{Floating: "auto_on"}, "floating enable"},
+		{"tiled by the user", models.LayoutNode{Floating: "user_off"}, "floating disable"},
+		{
+			"sticky floating window",
+			models.LayoutNode{Floating: "user_on", Sticky: true, Marks: []string{"a b"}},
+			`floating enable, mark --add "a b", sticky enable`,
+		},
+		{
+			"fullscreen last",
+			models.LayoutNode{Fullscreen: 2, TitleFormat: "<b>%title</b>"},
+			`title_format "<b>%title</b>", fullscreen enable global`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stateActions(&tt.node); got != tt.want {
+				t.Errorf("stateActions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// offlineClient is an i3 client that cannot reach i3.
+type offlineClient struct {
+	i3internal.Client
+}
+
+func (offlineClient) GetOutputs() ([]i3.Output, error) {
+	return nil, errors.New("i3 not running")
+}
+
+func TestDryRunWithoutI3(t *testing.T) {
+	st := store.NewMemoryStore()
+	snap := models.Snapshot{
+		SchemaVersion: models.SchemaVersion,
+		Name:          "work",
+		Workspaces: []models.WorkspaceSnapshot{{
+			Name:   "1",
+			Output: &models.OutputRef{Name: "DP-1", Rect: models.Rect{Width: 2560, Height: 1440}},
+			Root: models.LayoutNode{Type: "workspace", Nodes: []models.LayoutNode{
+				{ID: 2, Type: "con", WindowID: 0x1000001, WindowClass: "kitty"},
+			}},
+			Windows: []models.WindowRef{{NodeID: 2, Class: "kitty", Argv: []string{"kitty"}}},
+		}},
+	}
+	if err := st.Put("work", snap); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	err := Restore(st, "work", RestoreOptions{
+		DryRun:    true,
+		Out:       &out,
+		Client:    offlineClient{},
+		OutputMap: map[string]string{"DP-1": "DP-1"},
+	})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	for _, want := range []string{"i3 is not reachable", "move workspace to output DP-1", "launch: kitty"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("dry run output misses %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package snapshot
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/a9sk/i3-snapshot/internal/i3/i3test"
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"github.com/a9sk/i3-snapshot/internal/proc"
+	"github.com/a9sk/i3-snapshot/internal/store"
+	"go.i3wm.org/i3"
+)
+
+// fakeApp is an application the tests "launch" by opening its window on a fake i3.
+type fakeApp struct {
+	argv                   []string
+	class, instance, title string
+}
+
+var fakeApps = []fakeApp{
+	{[]string{"alacritty"}, "Alacritty", "Alacritty", "term"},
+	{[]string{"firefox"}, "firefox", "Navigator", "web"},
+	{[]string{"mpv", "video.mkv"}, "mpv", "gl", "video"},
+}
+
+// openApp opens the window of app on srv and adds its WM_COMMAND to windows.
+func openApp(t *testing.T, srv *i3test.Server, app fakeApp, windows proc.FakeInspector) {
+	t.Helper()
+	con := srv.OpenWindow(app.class, app.instance, app.title)
+	tree, err := srv.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	windows[uint32(findNode(tree.Root, con).Window)] = proc.WindowFixture{Command: app.argv}
+}
+
+// findNode returns the container with the given ID.
+func findNode(n *i3.Node, id i3.NodeID) *i3.Node {
+	if n.ID == id {
+		return n
+	}
+	for _, children := range [][]*i3.Node{n.Nodes, n.FloatingNodes} {
+		for _, c := range children {
+			if found := findNode(c, id); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// launcher returns a RestoreOptions.Start opening the window of the launched fake app.
+func launcher(t *testing.T, srv *i3test.Server) func(cmd *exec.Cmd) error {
+	return func(cmd *exec.Cmd) error {
+		for _, app := range fakeApps {
+			if app.argv[0] == cmd.Args[0] {
+				go srv.OpenWindow(app.class, app.instance, app.title)
+				return nil
+			}
+		}
+		t.Errorf("unexpected launch of %v", cmd.Args)
+		return nil
+	}
+}
+
+// workspaceClasses returns the window classes on every workspace of the tree, by name.
+func workspaceClasses(root *i3.Node) map[string][]string {
+	classes := make(map[string][]string)
+	for _, w := range workspaceWindows(root) {
+		classes[w.workspace] = append(classes[w.workspace], w.node.WindowProperties.Class)
+	}
+	return classes
+}
+
+func TestSaveRestoreRoundTrip(t *testing.T) {
+	src := i3test.Start(t, "DP-1", "DP-2")
+	windows := proc.FakeInspector{}
+	openApp(t, src, fakeApps[0], windows)
+	openApp(t, src, fakeApps[1], windows)
+	if _, err := i3.RunCommand("workspace 5; move workspace to output DP-2"); err != nil {
+		t.Fatal(err)
+	}
+	openApp(t, src, fakeApps[2], windows)
+
+	st := store.NewMemoryStore()
+	if err := Save(st, "session", SaveOptions{Windows: windows}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	snap, err := st.Get("session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := make(map[string]models.WorkspaceSnapshot)
+	for _, ws := range snap.Workspaces {
+		saved[ws.Name] = ws
+	}
+	if got := saved["1"]; got.Output == nil || got.Output.Name != "DP-1" || len(got.Windows) != 2 {
+		t.Errorf("saved workspace 1 = %+v, want 2 windows on DP-1", got)
+	}
+	if got := saved["5"]; got.Output == nil || got.Output.Name != "DP-2" || len(got.Windows) != 1 ||
+		len(got.Windows[0].Argv) != 2 || got.Windows[0].Argv[1] != "video.mkv" {
+		t.Errorf("saved workspace 5 = %+v, want mpv video.mkv on DP-2", got)
+	}
+
+	// a fresh session on the same monitors; the old one has to go first, go.i3wm.org/i3
+	// keeps its connection
+	src.Close()
+	dst := i3test.Start(t, "DP-1", "DP-2")
+
+	if err := Restore(st, "session", RestoreOptions{Start: launcher(t, dst)}); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	tree, err := dst.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	classes := workspaceClasses(tree.Root)
+	if got := classes["1"]; len(got) != 2 || got[0] != "Alacritty" || got[1] != "firefox" {
+		t.Errorf("restored workspace 1 holds %v, want [Alacritty firefox]", got)
+	}
+	if got := classes["5"]; len(got) != 1 || got[0] != "mpv" {
+		t.Errorf("restored workspace 5 holds %v, want [mpv]", got)
+	}
+	for _, name := range []string{"1", "5"} {
+		if ws := findWorkspace(tree.Root, name); ws == nil {
+			t.Errorf("workspace %s missing after restore", name)
+		} else if p := findPlaceholders(ws); len(p) > 0 {
+			t.Errorf("workspace %s still has %d placeholders", name, len(p))
+		}
+	}
+
+	workspaces, err := i3.GetWorkspaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ws := range workspaces {
+		if ws.Name == "5" && ws.Output != "DP-2" {
+			t.Errorf("workspace 5 restored on %s, want DP-2", ws.Output)
+		}
+	}
+}
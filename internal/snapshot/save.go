@@ -1,163 +1,322 @@
 package snapshot
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
 	i3internal "github.com/a9sk/i3-snapshot/internal/i3"
 	"github.com/a9sk/i3-snapshot/internal/models"
 	"github.com/a9sk/i3-snapshot/internal/proc"
+	"github.com/a9sk/i3-snapshot/internal/store"
 	"go.i3wm.org/i3"
 )
 
-// Save captures all workspace layouts and associated commands into a JSON file.
-func Save(name string) error {
-	tree := i3internal.GetTree()
-	if tree.Root == nil {
-		return fmt.Errorf("i3 tree root is nil")
-	}
+// SaveOptions tweaks what a snapshot captures.
+type SaveOptions struct {
+	// EnvAllow and EnvDeny extend the default patterns selecting which environment
+	// variables of each window's process are recorded (see proc.DefaultEnvFilter).
+	EnvAllow []string
+	EnvDeny  []string
 
-	// collect all workspaces from the tree
-	workspaces := getAllWorkspaces(tree.Root)
-	if len(workspaces) == 0 {
-		return fmt.Errorf("no workspaces found in i3 tree")
-	}
+	// Description is free text stored in the snapshot metadata.
+	Description string
+	// ToolVersion is the i3-snapshot version recorded in the snapshot metadata.
+	ToolVersion string
 
-	snap := buildSnapshot(name, workspaces)
+	// Focused, Workspaces and Outputs limit the snapshot to the focused workspace, the
+	// workspaces with these names and the workspaces on these outputs; a workspace matching
+	// any of them is saved. Without any of them every workspace and the scratchpad are saved.
+	Focused    bool
+	Workspaces []string
+	Outputs    []string
 
-	// resolve output path: ~/.config/i3-snapshot/saves/<name>.json
-	configDir, err := os.UserConfigDir()
-	if err != nil {
-		return fmt.Errorf("resolving config dir: %w", err)
-	}
-	saveDir := filepath.Join(configDir, "i3-snapshot", "saves")
-	if err := os.MkdirAll(saveDir, 0o755); err != nil {
-		return fmt.Errorf("creating save dir %s: %w", saveDir, err)
-	}
+	// Client is the i3 to capture; nil means the running i3.
+	Client i3internal.Client
+	// Windows reads the X11 properties of the windows; nil means the X server in $DISPLAY.
+	Windows proc.WindowInspector
+	// ProcRoot is where the processes behind the windows are looked up; empty means /proc.
+	ProcRoot string
+}
 
-	outPath := filepath.Join(saveDir, name+".json")
-	f, err := os.Create(outPath)
-	if err != nil {
-		return fmt.Errorf("creating snapshot file %s: %w", outPath, err)
+// client returns the i3 client to use.
+func (o SaveOptions) client() i3internal.Client {
+	if o.Client != nil {
+		return o.Client
 	}
-	defer f.Close()
+	return i3internal.DefaultClient()
+}
 
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(snap); err != nil {
-		return fmt.Errorf("encoding snapshot: %w", err)
-	}
+// filtered reports whether the options select a subset of the workspaces.
+func (o SaveOptions) filtered() bool {
+	return o.Focused || len(o.Workspaces) > 0 || len(o.Outputs) > 0
+}
+
+// captureContext holds what convertNode needs besides the tree itself.
+type captureContext struct {
+	states   map[int64]i3internal.ContainerState // extra container state by container ID
+	env      proc.EnvFilter                      // environment variables to record per window
+	windows  proc.WindowInspector                // X11 properties of the windows
+	procfs   proc.FS                             // processes behind the windows
+	hostname string                              // this machine, to tell windows of remote clients apart
+}
 
-	return nil
+// Save captures all workspace layouts and associated commands and puts them in the store.
+func Save(st store.SnapshotStore, name string, opts SaveOptions) error {
+	snap, err := capture(name, opts)
+	if err != nil {
+		return err
+	}
+	return st.Put(name, snap)
 }
 
-// getWorkspaceTree pulls the current i3 tree and returns the focused workspace node.
-// Returns an error if no focused workspace is found.
-// Note: The focused node is usually a window (leaf), not the workspace container.
-// We track the current workspace as we walk and return it when we find a focused node.
-func getWorkspaceTree() (*i3.Node, error) {
-	tree := i3internal.GetTree()
+// capture builds a snapshot of the current i3 session without storing it.
+func capture(name string, opts SaveOptions) (models.Snapshot, error) {
+	client := opts.client()
 
+	// marks, sticky, fullscreen etc. are not part of go.i3wm.org/i3's tree, so they come
+	// decoded separately from the same reply
+	tree, states, err := client.GetTreeWithState()
+	if err != nil {
+		return models.Snapshot{}, err
+	}
 	if tree.Root == nil {
-		return nil, fmt.Errorf("i3 tree root is nil")
+		return models.Snapshot{}, fmt.Errorf("i3 tree root is nil")
 	}
 
-	var focusedWorkspace *i3.Node
+	// collect all workspaces from the tree
+	workspaces := getAllWorkspaces(tree.Root)
+	if len(workspaces) == 0 {
+		return models.Snapshot{}, fmt.Errorf("no workspaces found in i3 tree")
+	}
+	workspaces, err = selectWorkspaces(workspaces, opts)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
 
-	var walk func(n *i3.Node, currentWS *i3.Node)
-	walk = func(n *i3.Node, currentWS *i3.Node) {
-		if n == nil || focusedWorkspace != nil {
-			return
-		}
+	// the scratchpad is not tied to any workspace, so a partial snapshot leaves it out
+	var scratch *i3.Node
+	if !opts.filtered() {
+		scratch = findScratchpad(tree.Root)
+	}
 
-		if n.Type == i3.WorkspaceNode {
-			currentWS = n
+	// the tree does not know which output is primary, ask i3 separately;
+	// this is best-effort, a snapshot without it is still perfectly usable
+	primary := ""
+	outputs, _ := client.GetOutputs()
+	for _, o := range outputs {
+		if o.Primary {
+			primary = o.Name
 		}
+	}
 
-		// check if THIS node is the one with focus
-		if n.Focused {
-			// f the workspace itself is focused (empty), currentWS is n
-			// if a winow inside is focused, currentWS is the parent workspace
-			focusedWorkspace = currentWS
-			return
-		}
+	windows := opts.Windows
+	if windows == nil {
+		x := proc.NewX11Inspector()
+		defer x.Close()
+		windows = x
+	}
+	hostname, _ := os.Hostname()
 
-		// recurse into children, passing down the current workspace
-		for i := range n.Nodes {
-			walk(n.Nodes[i], currentWS)
-		}
-		for i := range n.FloatingNodes {
-			walk(n.FloatingNodes[i], currentWS)
-		}
+	ctx := &captureContext{
+		states:   states,
+		env:      proc.DefaultEnvFilter(opts.EnvAllow, opts.EnvDeny),
+		windows:  windows,
+		procfs:   proc.NewFS(opts.ProcRoot),
+		hostname: hostname,
 	}
 
-	walk(tree.Root, nil)
+	snap := buildSnapshot(name, workspaces, scratch, primary, ctx)
+	snap.Metadata = buildMetadata(snap, outputs, client, opts)
+	return snap, nil
+}
 
-	if focusedWorkspace == nil {
-		return nil, fmt.Errorf("no focused workspace found in i3 tree")
-	}
-	return focusedWorkspace, nil
+// workspaceRef pairs a workspace node with the output node it lives on.
+type workspaceRef struct {
+	node    *i3.Node
+	output  *i3.Node
+	visible bool // shown on its output
+	focused bool // holds the global focus
 }
 
-// getAllWorkspaces collects all workspace nodes from the i3 tree.
+// getAllWorkspaces collects all workspace nodes from the i3 tree together with their output.
 // Filters out internal i3 workspaces (like __i3_scratch) that cannot be switched to.
-func getAllWorkspaces(root *i3.Node) []*i3.Node {
-	var workspaces []*i3.Node
-	var walk func(n *i3.Node)
-	walk = func(n *i3.Node) {
+func getAllWorkspaces(root *i3.Node) []workspaceRef {
+	var workspaces []workspaceRef
+	var walk func(n, parent, output *i3.Node)
+	walk = func(n, parent, output *i3.Node) {
 		if n == nil {
 			return
 		}
+		if n.Type == i3.OutputNode {
+			output = n
+		}
 		if n.Type == i3.WorkspaceNode {
 			// skip internal i3 workspaces (they start with __i3_)
 			if !strings.HasPrefix(n.Name, "__i3_") {
-				workspaces = append(workspaces, n)
+				workspaces = append(workspaces, workspaceRef{
+					node:   n,
+					output: output,
+					// the output's content container focuses the workspace it shows
+					visible: parent != nil && len(parent.Focus) > 0 && parent.Focus[0] == n.ID,
+					focused: hasFocus(n),
+				})
 			}
 		}
 		for i := range n.Nodes {
-			walk(n.Nodes[i])
+			walk(n.Nodes[i], n, output)
 		}
 		for i := range n.FloatingNodes {
-			walk(n.FloatingNodes[i])
+			walk(n.FloatingNodes[i], n, output)
 		}
 	}
-	walk(root)
+	walk(root, nil, nil)
 	return workspaces
 }
 
+// selectWorkspaces applies the Focused, Workspaces and Outputs filters of opts.
+// Naming a workspace or output that does not exist is an error, as is selecting nothing.
+func selectWorkspaces(workspaces []workspaceRef, opts SaveOptions) ([]workspaceRef, error) {
+	if !opts.filtered() {
+		return workspaces, nil
+	}
+
+	wantWS := make(map[string]bool, len(opts.Workspaces))
+	for _, name := range opts.Workspaces {
+		wantWS[name] = false
+	}
+	wantOut := make(map[string]bool, len(opts.Outputs))
+	for _, name := range opts.Outputs {
+		wantOut[name] = false
+	}
+
+	var selected []workspaceRef
+	for _, ws := range workspaces {
+		output := ""
+		if ws.output != nil {
+			output = ws.output.Name
+		}
+		_, byName := wantWS[ws.node.Name]
+		_, byOutput := wantOut[output]
+		if byName {
+			wantWS[ws.node.Name] = true
+		}
+		if byOutput {
+			wantOut[output] = true
+		}
+		if byName || byOutput || (opts.Focused && ws.focused) {
+			selected = append(selected, ws)
+		}
+	}
+
+	for _, name := range opts.Workspaces {
+		if !wantWS[name] {
+			return nil, fmt.Errorf("workspace %q not found", name)
+		}
+	}
+	for _, name := range opts.Outputs {
+		if !wantOut[name] {
+			return nil, fmt.Errorf("no workspaces on output %q", name)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no workspace matches the selection")
+	}
+	return selected, nil
+}
+
+// hasFocus reports whether n or any of its descendants is the focused container.
+func hasFocus(n *i3.Node) bool {
+	if n.Focused {
+		return true
+	}
+	for i := range n.Nodes {
+		if hasFocus(n.Nodes[i]) {
+			return true
+		}
+	}
+	for i := range n.FloatingNodes {
+		if hasFocus(n.FloatingNodes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
 // buildSnapshot converts multiple i3 workspace nodes + /proc data into the Snapshot model.
-func buildSnapshot(name string, workspaces []*i3.Node) models.Snapshot {
+// scratch is the __i3_scratch workspace and may be nil; primaryOutput is the name of the
+// primary output, if known.
+func buildSnapshot(name string, workspaces []workspaceRef, scratch *i3.Node, primaryOutput string, ctx *captureContext) models.Snapshot {
 	snap := models.Snapshot{
-		Name: name,
+		SchemaVersion: models.SchemaVersion,
+		Name:          name,
 	}
 
 	for _, ws := range workspaces {
 		var windows []models.WindowRef
-		root, windows := convertNode(ws)
+		root, windows := convertNode(ws.node, ctx)
+
+		var output *models.OutputRef
+		if ws.output != nil {
+			output = &models.OutputRef{
+				Name:    ws.output.Name,
+				Rect:    convertRect(ws.output.Rect),
+				Primary: ws.output.Name == primaryOutput,
+			}
+		}
+
 		snap.Workspaces = append(snap.Workspaces, models.WorkspaceSnapshot{
-			Name:    ws.Name,
+			Name:    ws.node.Name,
+			Output:  output,
+			Visible: ws.visible,
+			Focused: ws.focused,
 			Root:    root,
 			Windows: windows,
 		})
 	}
 
+	if scratch != nil {
+		root, windows := convertNode(scratch, ctx)
+		if len(windows) > 0 {
+			snap.Scratchpad = &models.ScratchpadSnapshot{
+				Root:    root,
+				Windows: windows,
+			}
+		}
+	}
+
 	return snap
 }
 
+// convertRect converts an i3 rectangle into our model.
+func convertRect(r i3.Rect) models.Rect {
+	return models.Rect{X: int(r.X), Y: int(r.Y), Width: int(r.Width), Height: int(r.Height)}
+}
+
 // convertNode walks an i3.Node tree and returns the LayoutNode plus a flat list of WindowRefs.
-func convertNode(n *i3.Node) (models.LayoutNode, []models.WindowRef) {
+func convertNode(n *i3.Node, ctx *captureContext) (models.LayoutNode, []models.WindowRef) {
 	node := models.LayoutNode{
 		ID:       int64(n.ID),
 		Type:     string(n.Type),
 		Layout:   string(n.Layout),
 		Name:     n.Name,
 		Border:   string(n.Border),
-		Rect:     models.Rect{X: int(n.Rect.X), Y: int(n.Rect.Y), Width: int(n.Rect.Width), Height: int(n.Rect.Height)},
+		Rect:     convertRect(n.Rect),
 		WindowID: int(n.Window),
 		Focused:  n.Focused,
+		Percent:  n.Percent,
+	}
+
+	for _, id := range n.Focus {
+		node.Focus = append(node.Focus, int64(id))
+	}
+
+	if st, ok := ctx.states[int64(n.ID)]; ok {
+		node.Marks = st.Marks
+		node.Fullscreen = st.FullscreenMode
+		node.Sticky = st.Sticky
+		node.Floating = st.Floating
+		node.TitleFormat = st.TitleFormat
 	}
 
 	var allWindows []models.WindowRef
@@ -166,6 +325,22 @@ func convertNode(n *i3.Node) (models.LayoutNode, []models.WindowRef) {
 	// skip system windows that shouldn't be restored (i3bar, cursor, etc.)
 	if n.Window != 0 {
 		wp := n.WindowProperties
+		xid := uint32(n.Window)
+		// i3 normally knows the class, ask the window itself when it does not
+		if wp.Class == "" || wp.Instance == "" {
+			if class, instance, err := ctx.windows.Class(xid); err == nil {
+				if wp.Class == "" {
+					wp.Class = class
+				}
+				if wp.Instance == "" {
+					wp.Instance = instance
+				}
+			}
+		}
+		if wp.Role == "" {
+			wp.Role, _ = ctx.windows.Role(xid)
+		}
+
 		// filter out system windows that shouldn't be restored
 		skipWindow := wp.Class == "i3bar" || wp.Instance == "i3bar" || wp.Class == "i3status" ||
 			wp.Class == "" || wp.Instance == "" // windows without class/instance are likely invalid
@@ -177,42 +352,102 @@ func convertNode(n *i3.Node) (models.LayoutNode, []models.WindowRef) {
 			node.WindowInst = wp.Instance
 			node.WindowTitle = wp.Title
 
-			// resolve PID via X11 (_NET_WM_PID) using the X11 window id from n.Window
-			// errors are treated as "no PID available" so snapshots remain usable
-			cmd := ""
-			cwd := ""
-			if pid, err := proc.GetPIDFromWindowID(uint32(n.Window)); err == nil && pid > 0 {
-				if c, e := proc.GetCommandFromPID(pid); e == nil {
-					cmd = c
-				}
-				if d, e := proc.GetCWDFromPID(pid); e == nil {
-					cwd = d
-				}
-			}
-
 			w := models.WindowRef{
 				NodeID:   int64(n.ID),
 				Class:    wp.Class,
 				Instance: wp.Instance,
 				Title:    wp.Title,
-				Command:  cmd,
-				Cwd:      cwd,
+				Role:     wp.Role,
+			}
+
+			// the PID of a client on another machine means nothing in our /proc
+			machine, _ := ctx.windows.ClientMachine(xid)
+			if machine != "" && ctx.hostname != "" && !sameHost(machine, ctx.hostname) {
+				w.Machine = machine
+			} else if pid, err := ctx.windows.PID(xid); err == nil && pid > 0 {
+				// errors are treated as "no PID available" so snapshots remain usable
+				captureProcess(&w, pid, ctx)
 			}
+
+			// WM_COMMAND is all we know about remote clients and those without a PID
+			if len(w.Argv) == 0 {
+				if argv, err := ctx.windows.Command(xid); err == nil {
+					w.Argv = argv
+				}
+			}
+			w.Command = strings.Join(w.Argv, " ")
+
 			allWindows = append(allWindows, w)
 		}
 	}
 
 	// recurse into tiling and floating children
 	for i := range n.Nodes {
-		childNode, childWindows := convertNode(n.Nodes[i])
+		childNode, childWindows := convertNode(n.Nodes[i], ctx)
 		node.Nodes = append(node.Nodes, childNode)
 		allWindows = append(allWindows, childWindows...)
 	}
 	for i := range n.FloatingNodes {
-		childNode, childWindows := convertNode(n.FloatingNodes[i])
+		childNode, childWindows := convertNode(n.FloatingNodes[i], ctx)
 		node.FloatingNodes = append(node.FloatingNodes, childNode)
 		allWindows = append(allWindows, childWindows...)
 	}
 
 	return node, allWindows
 }
+
+// captureProcess records the command line, directory and environment of the process behind
+// a window and, for terminals, the shell inside and what it is running.
+func captureProcess(w *models.WindowRef, pid int, ctx *captureContext) {
+	if a, err := ctx.procfs.GetArgvFromPID(pid); err == nil {
+		w.Argv = a
+	}
+	if d, err := ctx.procfs.GetCWDFromPID(pid); err == nil {
+		w.Cwd = d
+	}
+	if env, err := ctx.procfs.GetEnvFromPID(pid, ctx.env); err == nil && len(env) > 0 {
+		w.Env = env
+	}
+
+	shellPID, jobPID, err := ctx.procfs.GetTerminalProcesses(pid)
+	if err != nil {
+		return
+	}
+	w.Shell = processRef(ctx.procfs, shellPID)
+	// the tmux/screen client is either the shell's foreground job, or the terminal runs
+	// it directly ("alacritty -e tmux") and it leads the session itself
+	clientPID := shellPID
+	if jobPID != 0 {
+		w.Job = processRef(ctx.procfs, jobPID)
+		clientPID = jobPID
+	}
+	// a tmux/screen client: remember the session rather than the client
+	if m, err := ctx.procfs.GetMultiplexerFromPID(clientPID); err == nil {
+		w.Multiplexer = &models.MultiplexerRef{
+			Kind:       m.Kind,
+			Session:    m.Session,
+			SocketName: m.SocketName,
+			SocketPath: m.SocketPath,
+		}
+	}
+}
+
+// processRef records argv and cwd of a process, or returns nil if it cannot be read.
+func processRef(fs proc.FS, pid int) *models.ProcessRef {
+	argv, err := fs.GetArgvFromPID(pid)
+	if err != nil {
+		return nil
+	}
+	cwd, _ := fs.GetCWDFromPID(pid)
+	return &models.ProcessRef{Argv: argv, Cwd: cwd}
+}
+
+// sameHost reports whether two host names refer to the same machine; WM_CLIENT_MACHINE
+// may hold either the short or the fully qualified name.
+func sameHost(a, b string) bool {
+	short := func(h string) string {
+		h, _, _ = strings.Cut(h, ".")
+		return strings.ToLower(h)
+	}
+	return short(a) == short(b)
+}
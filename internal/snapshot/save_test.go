@@ -0,0 +1,168 @@
+package snapshot
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+	"github.com/a9sk/i3-snapshot/internal/proc"
+	"go.i3wm.org/i3"
+)
+
+// fakeProcess is a process in a fixture procfs directory.
+type fakeProcess struct {
+	pid, ppid, pgrp, session int
+	tty, tpgid               int
+	start                    uint64
+	argv                     []string
+	cwd                      string
+	environ                  []string
+	children                 []int
+}
+
+// writeProcs lays out procs below root the way /proc does.
+func writeProcs(t *testing.T, root string, procs ...fakeProcess) {
+	t.Helper()
+	for _, p := range procs {
+		dir := filepath.Join(root, strconv.Itoa(p.pid))
+		task := filepath.Join(dir, "task", strconv.Itoa(p.pid))
+		if err := os.MkdirAll(task, 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		stat := fmt.Sprintf("%d (%s) S %d %d %d %d %d%s %d\n", p.pid, filepath.Base(p.argv[0]),
+			p.ppid, p.pgrp, p.session, p.tty, p.tpgid, strings.Repeat(" 0", 13), p.start)
+		var children []string
+		for _, c := range p.children {
+			children = append(children, strconv.Itoa(c))
+		}
+		files := map[string]string{
+			filepath.Join(dir, "stat"):      stat,
+			filepath.Join(dir, "cmdline"):   strings.Join(p.argv, "\x00") + "\x00",
+			filepath.Join(dir, "environ"):   strings.Join(p.environ, "\x00"),
+			filepath.Join(task, "children"): strings.Join(children, " "),
+		}
+		for path, content := range files {
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if p.cwd != "" {
+			if err := os.Symlink(p.cwd, filepath.Join(dir, "cwd")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// windowNode returns an i3 window container.
+func windowNode(id i3.NodeID, xid int64, class, instance string) *i3.Node {
+	return &i3.Node{
+		ID: id, Type: i3.Con, Window: xid,
+		WindowProperties: i3.WindowProperties{Class: class, Instance: instance, Title: class},
+	}
+}
+
+func TestBuildSnapshot(t *testing.T) {
+	const tty = 34816 // /dev/pts/0
+	root := t.TempDir()
+	writeProcs(t, root,
+		// alacritty running bash, with nvim in the foreground
+		fakeProcess{pid: 100, ppid: 1, pgrp: 100, session: 100, argv: []string{"alacritty"}, cwd: "/home/me",
+			environ: []string{"LANG=C", "GITHUB_TOKEN=secret", "VIRTUAL_ENV=/venv", "DISPLAY=:0"}, children: []int{101}},
+		fakeProcess{pid: 101, ppid: 100, pgrp: 101, session: 101, tty: tty, tpgid: 102, start: 10,
+			argv: []string{"bash"}, cwd: "/src"},
+		fakeProcess{pid: 102, ppid: 101, pgrp: 102, session: 101, tty: tty, tpgid: 102, start: 20,
+			argv: []string{"nvim", "main.go"}, cwd: "/src/cmd"},
+		// kitty running tmux directly, attached to a pane of session work
+		fakeProcess{pid: 200, ppid: 1, pgrp: 200, session: 200, argv: []string{"kitty", "tmux", "attach", "-t", "work:1.0"},
+			cwd: "/home/me", children: []int{201}},
+		fakeProcess{pid: 201, ppid: 200, pgrp: 201, session: 201, tty: tty + 1, tpgid: 201, start: 30,
+			argv: []string{"tmux", "attach", "-t", "work:1.0"}, cwd: "/home/me"},
+	)
+
+	windows := proc.FakeInspector{
+		0x100: {PID: 100},
+		0x200: {PID: 200},
+		// forwarded over ssh -X: its PID 100 is a process on the other machine
+		0x300: {PID: 100, ClientMachine: "buildbox", Command: []string{"xterm"}},
+		0x400: {Command: []string{"xclock", "-digital"}},
+	}
+
+	ws := &i3.Node{ID: 10, Type: i3.WorkspaceNode, Name: "1", Nodes: []*i3.Node{
+		windowNode(11, 0x100, "Alacritty", "Alacritty"),
+		windowNode(12, 0x200, "kitty", "kitty"),
+		windowNode(13, 0x300, "XTerm", "xterm"),
+		windowNode(14, 0x400, "XClock", "xclock"),
+		windowNode(15, 0x500, "i3bar", "i3bar"),
+	}}
+	output := &i3.Node{ID: 2, Type: i3.OutputNode, Name: "DP-1", Rect: i3.Rect{Width: 1920, Height: 1080}}
+
+	ctx := &captureContext{
+		env:      proc.DefaultEnvFilter(nil, nil),
+		windows:  windows,
+		procfs:   proc.NewFS(root),
+		hostname: "laptop",
+	}
+	snap := buildSnapshot("work", []workspaceRef{{node: ws, output: output, focused: true}}, nil, "DP-1", ctx)
+
+	if len(snap.Workspaces) != 1 {
+		t.Fatalf("got %d workspaces, want 1", len(snap.Workspaces))
+	}
+	saved := snap.Workspaces[0]
+	if o := saved.Output; o == nil || o.Name != "DP-1" || !o.Primary || o.Rect.Width != 1920 {
+		t.Errorf("output = %+v, want primary DP-1", o)
+	}
+	got := make(map[string]models.WindowRef)
+	for _, w := range saved.Windows {
+		got[w.Class] = w
+	}
+	if len(got) != 4 {
+		t.Fatalf("got windows %v, want 4 (i3bar skipped)", slices.Collect(maps.Keys(got)))
+	}
+
+	term := got["Alacritty"]
+	if !slices.Equal(term.Argv, []string{"alacritty"}) || term.Cwd != "/home/me" {
+		t.Errorf("alacritty = %q in %s", term.Argv, term.Cwd)
+	}
+	if want := map[string]string{"LANG": "C", "VIRTUAL_ENV": "/venv"}; !maps.Equal(term.Env, want) {
+		t.Errorf("alacritty env = %v, want %v", term.Env, want)
+	}
+	if term.Shell == nil || !slices.Equal(term.Shell.Argv, []string{"bash"}) || term.Shell.Cwd != "/src" {
+		t.Errorf("alacritty shell = %+v, want bash in /src", term.Shell)
+	}
+	if term.Job == nil || !slices.Equal(term.Job.Argv, []string{"nvim", "main.go"}) || term.Job.Cwd != "/src/cmd" {
+		t.Errorf("alacritty job = %+v, want nvim main.go in /src/cmd", term.Job)
+	}
+
+	mux := got["kitty"]
+	if mux.Job != nil {
+		t.Errorf("kitty job = %+v, want none", mux.Job)
+	}
+	if m := mux.Multiplexer; m == nil || m.Kind != "tmux" || m.Session != "work" {
+		t.Errorf("kitty multiplexer = %+v, want tmux session work", m)
+	}
+
+	remote := got["XTerm"]
+	if remote.Machine != "buildbox" || !slices.Equal(remote.Argv, []string{"xterm"}) || remote.Cwd != "" || remote.Shell != nil {
+		t.Errorf("remote xterm = %+v, want WM_COMMAND only, from buildbox", remote)
+	}
+
+	clock := got["XClock"]
+	if !slices.Equal(clock.Argv, []string{"xclock", "-digital"}) || clock.Command != "xclock -digital" {
+		t.Errorf("xclock = %q (%q), want its WM_COMMAND", clock.Argv, clock.Command)
+	}
+
+	// the remote window is kept in the layout but not relaunched
+	for _, step := range BuildPlan(snap, i3.Tree{}, nil).Steps {
+		if l, ok := step.(Launch); ok && l.Window.Class == "XTerm" {
+			t.Errorf("remote window relaunched locally: %q", l.Argv)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package snapshot
+
+import "go.i3wm.org/i3"
+
+// scratchpadWorkspace is the name of the hidden workspace i3 keeps scratchpad windows in.
+const scratchpadWorkspace = "__i3_scratch"
+
+// findScratchpad returns the __i3_scratch workspace node, or nil if the tree has none.
+func findScratchpad(root *i3.Node) *i3.Node {
+	if root == nil {
+		return nil
+	}
+	if root.Type == i3.WorkspaceNode && root.Name == scratchpadWorkspace {
+		return root
+	}
+	for i := range root.Nodes {
+		if n := findScratchpad(root.Nodes[i]); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// collectWindows returns every container holding an X11 window, tiling or floating.
+func collectWindows(root *i3.Node) []*i3.Node {
+	var windows []*i3.Node
+	var walk func(n *i3.Node)
+	walk = func(n *i3.Node) {
+		if n == nil {
+			return
+		}
+		if n.Window != 0 {
+			windows = append(windows, n)
+		}
+		for i := range n.Nodes {
+			walk(n.Nodes[i])
+		}
+		for i := range n.FloatingNodes {
+			walk(n.FloatingNodes[i])
+		}
+	}
+	walk(root)
+	return windows
+}
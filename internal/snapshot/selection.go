@@ -0,0 +1,90 @@
+package snapshot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+)
+
+// selectWorkspacesToRestore drops the workspaces not listed in opts.Workspaces and renames
+// the rest according to opts.Rename and opts.Offset, so a snapshot can be restored onto
+// other workspaces than the ones it was taken from. The scratchpad is only restored
+// together with the whole snapshot.
+func selectWorkspacesToRestore(snap *models.Snapshot, opts RestoreOptions) error {
+	if len(opts.Workspaces) > 0 {
+		want := make(map[string]bool, len(opts.Workspaces))
+		for _, name := range opts.Workspaces {
+			want[name] = false
+		}
+
+		var kept []models.WorkspaceSnapshot
+		for _, ws := range snap.Workspaces {
+			if _, ok := want[ws.Name]; ok {
+				want[ws.Name] = true
+				kept = append(kept, ws)
+			}
+		}
+		for _, name := range opts.Workspaces {
+			if !want[name] {
+				return fmt.Errorf("workspace %q is not in snapshot %s", name, snap.Name)
+			}
+		}
+
+		snap.Workspaces = kept
+		snap.Scratchpad = nil
+	}
+
+	saved := make(map[string]bool, len(snap.Workspaces))
+	for _, ws := range snap.Workspaces {
+		saved[ws.Name] = true
+	}
+	for from := range opts.Rename {
+		if !saved[from] {
+			return fmt.Errorf("workspace %q is not in snapshot %s", from, snap.Name)
+		}
+	}
+
+	targets := make(map[string]string, len(snap.Workspaces))
+	for i := range snap.Workspaces {
+		ws := &snap.Workspaces[i]
+
+		target := ws.Name
+		if to, ok := opts.Rename[ws.Name]; ok {
+			target = to
+		} else if opts.Offset != 0 {
+			shifted, err := offsetWorkspaceName(ws.Name, opts.Offset)
+			if err != nil {
+				return err
+			}
+			target = shifted
+		}
+
+		if other, dup := targets[target]; dup {
+			return fmt.Errorf("workspaces %q and %q would both be restored as %q", other, ws.Name, target)
+		}
+		targets[target] = ws.Name
+		ws.Name = target
+	}
+	return nil
+}
+
+// offsetWorkspaceName adds offset to the number of a numbered workspace, keeping the rest
+// of its name ("3" becomes "7", "3:web" becomes "7:web" for an offset of 4). Workspaces
+// without a number ("chat") keep their name.
+func offsetWorkspaceName(name string, offset int) (string, error) {
+	digits := len(name) - len(strings.TrimLeft(name, "0123456789"))
+	if digits == 0 {
+		return name, nil
+	}
+
+	num, err := strconv.Atoi(name[:digits])
+	if err != nil {
+		return "", fmt.Errorf("workspace %q: %w", name, err)
+	}
+	if num+offset < 1 {
+		return "", fmt.Errorf("offsetting workspace %q by %d gives number %d", name, offset, num+offset)
+	}
+	return strconv.Itoa(num+offset) + name[digits:], nil
+}
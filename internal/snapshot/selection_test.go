@@ -0,0 +1,53 @@
+package snapshot
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+)
+
+func TestSelectWorkspacesToRestore(t *testing.T) {
+	saved := []string{"1", "2:web", "chat"}
+	tests := []struct {
+		name    string
+		opts    RestoreOptions
+		want    []string
+		wantErr bool
+	}{
+		{"everything", RestoreOptions{}, []string{"1", "2:web", "chat"}, false},
+		{"offset", RestoreOptions{Offset: 4}, []string{"5", "6:web", "chat"}, false},
+		{"offset and rename", RestoreOptions{Offset: 4, Rename: map[string]string{"chat": "9"}}, []string{"5", "6:web", "9"}, false},
+		{"subset", RestoreOptions{Workspaces: []string{"1", "chat"}, Offset: 4}, []string{"5", "chat"}, false},
+		{"offset below 1", RestoreOptions{Offset: -1}, nil, true},
+		{"rename clash", RestoreOptions{Rename: map[string]string{"1": "chat"}}, nil, true},
+		{"unknown workspace", RestoreOptions{Workspaces: []string{"3"}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snap := models.Snapshot{Name: "work"}
+			for _, name := range saved {
+				snap.Workspaces = append(snap.Workspaces, models.WorkspaceSnapshot{Name: name})
+			}
+
+			err := selectWorkspacesToRestore(&snap, tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("selectWorkspacesToRestore() succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, ws := range snap.Workspaces {
+				got = append(got, ws.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("restored workspaces = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
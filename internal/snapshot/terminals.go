@@ -0,0 +1,212 @@
+package snapshot
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+)
+
+// terminalAdapter describes how to tell a terminal emulator which directory to start in
+// and which program to run instead of a bare shell.
+type terminalAdapter struct {
+	// exe replaces the captured executable, for terminals whose windows belong to a
+	// server process that cannot simply be started again (gnome-terminal-server)
+	exe string
+	// dir returns the flags that set the working directory; nil if the terminal has none,
+	// in which case we rely on the process cwd
+	dir func(dir string) []string
+	// dirFlags are all spellings of the directory flag, removed from the captured argv
+	// before dir adds the new directory
+	dirFlags []string
+	// exec are the flags that introduce the program to run, which always goes last;
+	// nil means the program is passed as positional arguments
+	exec []string
+}
+
+// flagArg builds a dir func for flags taking the directory as a separate argument.
+func flagArg(flag string) func(string) []string {
+	return func(dir string) []string { return []string{flag, dir} }
+}
+
+// flagEq builds a dir func for flags taking the directory as --flag=dir.
+func flagEq(flag string) func(string) []string {
+	return func(dir string) []string { return []string{flag + "=" + dir} }
+}
+
+var (
+	alacritty = terminalAdapter{
+		dir:      flagArg("--working-directory"),
+		dirFlags: []string{"--working-directory"},
+		exec:     []string{"-e"},
+	}
+	kitty = terminalAdapter{
+		dir:      flagArg("--directory"),
+		dirFlags: []string{"--directory", "-d"},
+	}
+	gnomeTerminal = terminalAdapter{
+		exe:      "gnome-terminal",
+		dir:      flagEq("--working-directory"),
+		dirFlags: []string{"--working-directory"},
+		exec:     []string{"--"},
+	}
+	urxvt = terminalAdapter{
+		dir:      flagArg("-cd"),
+		dirFlags: []string{"-cd"},
+		exec:     []string{"-e"},
+	}
+	xterm = terminalAdapter{exec: []string{"-e"}}
+	foot  = terminalAdapter{
+		dir:      flagEq("--working-directory"),
+		dirFlags: []string{"--working-directory", "-D"},
+	}
+	st = terminalAdapter{exec: []string{"-e"}}
+)
+
+// terminalAdapters maps known terminals, by lowercased window class or executable name,
+// to their adapter. Terminals not listed here are relaunched with their captured argv.
+var terminalAdapters = map[string]terminalAdapter{
+	"alacritty":             alacritty,
+	"kitty":                 kitty,
+	"gnome-terminal":        gnomeTerminal,
+	"gnome-terminal-server": gnomeTerminal,
+	"urxvt":                 urxvt,
+	"urxvtc":                urxvt,
+	"rxvt-unicode":          urxvt,
+	"xterm":                 xterm,
+	"uxterm":                xterm,
+	"foot":                  foot,
+	"footclient":            foot,
+	"st":                    st,
+	"st-256color":           st,
+}
+
+// lookupTerminal finds the adapter for a window, first by window class, then by executable.
+func lookupTerminal(w models.WindowRef) (terminalAdapter, bool) {
+	if a, ok := terminalAdapters[strings.ToLower(w.Class)]; ok {
+		return a, true
+	}
+	if len(w.Argv) > 0 {
+		if a, ok := terminalAdapters[filepath.Base(w.Argv[0])]; ok {
+			return a, true
+		}
+	}
+	return terminalAdapter{}, false
+}
+
+// launchArgv returns the argv used to relaunch a window. For known terminals with a
+// recorded shell it adds the flags that open the terminal in the shell's directory and
+// run the saved foreground job, or reattach its tmux/screen session; everything else is
+// launched with its captured argv.
+func launchArgv(w models.WindowRef) []string {
+	if w.Shell == nil || len(w.Argv) == 0 {
+		return w.Argv
+	}
+	a, ok := lookupTerminal(w)
+	if !ok {
+		return w.Argv
+	}
+
+	var job []string
+	switch {
+	case w.Multiplexer != nil:
+		job = jobArgv(multiplexerArgv(w.Multiplexer))
+	case w.Job != nil:
+		job = jobArgv(w.Job.Argv)
+	}
+	return a.argv(w.Argv, w.Shell.Argv, w.Shell.Cwd, job)
+}
+
+// argv rewrites the captured terminal argv to start in dir and run job (if not empty).
+// The flags go right after the executable, and any directory flag that was captured is
+// dropped, so it is not given twice. If the terminal was started with a program (e.g.
+// "alacritty -e htop" or "kitty htop") and there is no job, that program is kept.
+func (a terminalAdapter) argv(captured, shell []string, dir string, job []string) []string {
+	exe, opts := captured[0], captured[1:]
+	if a.exe != "" {
+		// the captured argv is the one of a server process, none of it applies
+		exe, opts = a.exe, nil
+	}
+	opts, program := a.splitProgram(opts, shell)
+	if len(job) > 0 {
+		program = job
+	}
+
+	out := []string{exe}
+	if dir != "" && a.dir != nil {
+		out = append(out, a.dir(dir)...)
+		opts = stripFlags(opts, a.dirFlags)
+	}
+	out = append(out, opts...)
+	if len(program) > 0 {
+		out = append(out, a.exec...)
+		out = append(out, program...)
+	}
+	return out
+}
+
+// splitProgram splits the captured terminal options from the program the terminal was
+// started with, if any: everything after the exec flag, or else the argv of the process
+// the terminal runs (shell) at the very end, for terminals taking the program positionally.
+func (a terminalAdapter) splitProgram(opts, shell []string) ([]string, []string) {
+	if len(a.exec) > 0 {
+		for i, arg := range opts {
+			if arg == a.exec[0] {
+				return opts[:i], opts[i+len(a.exec):]
+			}
+		}
+	}
+	if n := len(shell); n > 0 && n <= len(opts) && slices.Equal(opts[len(opts)-n:], shell) {
+		return opts[:len(opts)-n], opts[len(opts)-n:]
+	}
+	return opts, nil
+}
+
+// stripFlags removes the given flags from args, both as "--flag value" and "--flag=value".
+func stripFlags(args, flags []string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if slices.Contains(flags, arg) {
+			i++ // skip the value too
+			continue
+		}
+		if name, _, ok := strings.Cut(arg, "="); ok && slices.Contains(flags, name) {
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
+
+// jobArgv wraps a foreground job so the terminal drops back into the user's shell when
+// the job exits, instead of closing. The job is passed as positional parameters, so no
+// quoting is involved.
+func jobArgv(job []string) []string {
+	if len(job) == 0 {
+		return nil
+	}
+	return append([]string{"sh", "-c", `"$@"; exec "${SHELL:-sh}"`, "sh"}, job...)
+}
+
+// multiplexerArgv builds the command that attaches to a saved tmux or screen session,
+// creating it if its server is gone.
+func multiplexerArgv(m *models.MultiplexerRef) []string {
+	switch m.Kind {
+	case "tmux":
+		argv := []string{"tmux"}
+		switch {
+		case m.SocketPath != "":
+			argv = append(argv, "-S", m.SocketPath)
+		case m.SocketName != "":
+			argv = append(argv, "-L", m.SocketName)
+		}
+		// -A attaches if the session exists and creates it otherwise
+		return append(argv, "new-session", "-A", "-s", m.Session)
+	case "screen":
+		// -D -R reattaches here, creating the session if it does not exist
+		return []string{"screen", "-D", "-R", "-S", m.Session}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package snapshot
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+)
+
+func TestLaunchArgv(t *testing.T) {
+	job := &models.ProcessRef{Argv: []string{"nvim", "main.go"}}
+	wrapped := jobArgv(job.Argv)
+	tmux := &models.MultiplexerRef{Kind: "tmux", Session: "work"}
+
+	tests := []struct {
+		name string
+		w    models.WindowRef
+		want []string
+	}{
+		{
+			name: "not a terminal",
+			w: models.WindowRef{
+				Class: "Firefox", Argv: []string{"firefox", "--new-window"},
+				Shell: &models.ProcessRef{Argv: []string{"bash"}, Cwd: "/src"},
+			},
+			want: []string{"firefox", "--new-window"},
+		},
+		{
+			name: "alacritty shell",
+			w: models.WindowRef{
+				Class: "Alacritty", Argv: []string{"alacritty", "--class", "dev"},
+				Shell: &models.ProcessRef{Argv: []string{"-bash"}, Cwd: "/src"},
+			},
+			want: []string{"alacritty", "--working-directory", "/src", "--class", "dev"},
+		},
+		{
+			name: "alacritty drops the captured directory",
+			w: models.WindowRef{
+				Class: "Alacritty", Argv: []string{"alacritty", "--working-directory", "/old"},
+				Shell: &models.ProcessRef{Argv: []string{"-bash"}, Cwd: "/src"}, Job: job,
+			},
+			want: append([]string{"alacritty", "--working-directory", "/src", "-e"}, wrapped...),
+		},
+		{
+			name: "alacritty replaces the captured program with the job",
+			w: models.WindowRef{
+				Class: "Alacritty", Argv: []string{"alacritty", "-e", "bash", "-l"},
+				Shell: &models.ProcessRef{Argv: []string{"bash", "-l"}, Cwd: "/src"}, Job: job,
+			},
+			want: append([]string{"alacritty", "--working-directory", "/src", "-e"}, wrapped...),
+		},
+		{
+			name: "urxvt keeps the captured program",
+			w: models.WindowRef{
+				Class: "URxvt", Argv: []string{"urxvt", "-cd", "/old", "-e", "htop"},
+				Shell: &models.ProcessRef{Argv: []string{"htop"}, Cwd: "/src"},
+			},
+			want: []string{"urxvt", "-cd", "/src", "-e", "htop"},
+		},
+		{
+			name: "kitty positional program",
+			w: models.WindowRef{
+				Class: "kitty", Argv: []string{"kitty", "htop"},
+				Shell: &models.ProcessRef{Argv: []string{"htop"}, Cwd: "/src"},
+			},
+			want: []string{"kitty", "--directory", "/src", "htop"},
+		},
+		{
+			name: "kitty multiplexer",
+			w: models.WindowRef{
+				Class: "kitty", Argv: []string{"kitty", "-d", "/old", "tmux"},
+				Shell: &models.ProcessRef{Argv: []string{"tmux"}, Cwd: "/src"}, Multiplexer: tmux,
+			},
+			want: append([]string{"kitty", "--directory", "/src"}, jobArgv(multiplexerArgv(tmux))...),
+		},
+		{
+			name: "foot drops the captured directory",
+			w: models.WindowRef{
+				Class: "foot", Argv: []string{"foot", "--working-directory=/old"},
+				Shell: &models.ProcessRef{Argv: []string{"-zsh"}, Cwd: "/src"}, Job: job,
+			},
+			want: append([]string{"foot", "--working-directory=/src"}, wrapped...),
+		},
+		{
+			name: "gnome-terminal server",
+			w: models.WindowRef{
+				Class: "Gnome-terminal", Argv: []string{"/usr/libexec/gnome-terminal-server"},
+				Shell: &models.ProcessRef{Argv: []string{"bash"}, Cwd: "/src"}, Job: job,
+			},
+			want: append([]string{"gnome-terminal", "--working-directory=/src", "--"}, wrapped...),
+		},
+		{
+			name: "xterm without directory flag",
+			w: models.WindowRef{
+				Class: "XTerm", Argv: []string{"xterm", "-fa", "Mono"},
+				Shell: &models.ProcessRef{Argv: []string{"bash"}, Cwd: "/src"}, Job: job,
+			},
+			want: append([]string{"xterm", "-fa", "Mono", "-e"}, wrapped...),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := launchArgv(tt.w); !slices.Equal(got, tt.want) {
+				t.Errorf("launchArgv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package snapshot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	i3internal "github.com/a9sk/i3-snapshot/internal/i3"
+	"go.i3wm.org/i3"
+)
+
+// subscribeTimeout is how long to wait for i3 to confirm an event subscription.
+const subscribeTimeout = 2 * time.Second
+
+// windowWatch collects window::new and window::move events in the background, so the
+// subscription keeps being read while the executor is busy with other steps.
+type windowWatch struct {
+	recv i3internal.EventReceiver
+
+	mu     sync.Mutex
+	events []*i3.WindowEvent
+
+	started   chan struct{} // closed once i3 has confirmed the subscription
+	startOnce sync.Once     // i3 confirms again after reconnecting
+	ready     chan struct{} // signalled when events are queued
+	done      chan struct{} // closed when the subscription has ended
+}
+
+// watchWindows subscribes to window events and returns once the subscription is active.
+// i3 answers a tick subscription with a first tick event, so when that arrives no later
+// window event can be missed.
+func watchWindows(client i3internal.Client) (*windowWatch, error) {
+	w := &windowWatch{
+		recv:    client.Subscribe(i3.WindowEventType, i3.TickEventType),
+		started: make(chan struct{}),
+		ready:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+
+	select {
+	case <-w.started:
+		return w, nil
+	case <-w.done:
+		err := w.recv.Err()
+		if err == nil {
+			err = fmt.Errorf("subscription ended")
+		}
+		return nil, fmt.Errorf("subscribing to i3 window events: %w", err)
+	case <-time.After(subscribeTimeout):
+		w.Close()
+		return nil, fmt.Errorf("subscribing to i3 window events: no confirmation from i3")
+	}
+}
+
+// run queues events until the subscription ends.
+func (w *windowWatch) run() {
+	defer close(w.done)
+	for w.recv.Next() {
+		switch ev := w.recv.Event().(type) {
+		case *i3.TickEvent:
+			if ev.First {
+				w.startOnce.Do(func() { close(w.started) })
+			}
+		case *i3.WindowEvent:
+			if ev.Change != "new" && ev.Change != "move" {
+				continue
+			}
+			w.mu.Lock()
+			w.events = append(w.events, ev)
+			w.mu.Unlock()
+
+			select {
+			case w.ready <- struct{}{}:
+			default: // already signalled
+			}
+		}
+	}
+}
+
+// take returns the events queued since the last call.
+func (w *windowWatch) take() []*i3.WindowEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	events := w.events
+	w.events = nil
+	return events
+}
+
+// Close ends the subscription and waits for run to return.
+func (w *windowWatch) Close() {
+	w.recv.Close()
+	<-w.done
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+)
+
+// FileStore keeps each snapshot as <dir>/<name>.json, readable by the owner only.
+// Writes go to a temporary file that is renamed into place, so a crash or a full disk
+// never leaves a truncated snapshot behind.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a store rooted at dir. The directory is created on the first write.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// DirEnv is the environment variable that overrides the snapshot directory.
+const DirEnv = "I3_SNAPSHOT_DIR"
+
+// DefaultDir returns the directory snapshots are saved in: $I3_SNAPSHOT_DIR if set,
+// otherwise $XDG_DATA_HOME/i3-snapshot/saves (~/.local/share/i3-snapshot/saves).
+// Older versions saved to ~/.config/i3-snapshot/saves; that directory keeps being used
+// as long as it exists and the new one does not, so existing snapshots are not lost.
+func DefaultDir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir, nil
+	}
+
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("resolving home dir: %w", err)
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+	dir := filepath.Join(dataDir, "i3-snapshot", "saves")
+
+	if configDir, err := os.UserConfigDir(); err == nil {
+		legacy := filepath.Join(configDir, "i3-snapshot", "saves")
+		if !exists(dir) && exists(legacy) {
+			return legacy, nil
+		}
+	}
+	return dir, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Dir returns the directory the store keeps its files in.
+func (s *FileStore) Dir() string {
+	return s.dir
+}
+
+// path returns the file a snapshot called name is stored in.
+func (s *FileStore) path(name string) (string, error) {
+	if err := validName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, name+".json"), nil
+}
+
+// Get reads and decodes the snapshot called name. The snapshot is returned as stored:
+// callers are responsible for migrating older schema versions.
+func (s *FileStore) Get(name string) (models.Snapshot, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return models.Snapshot{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return models.Snapshot{}, fmt.Errorf("opening snapshot %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var snap models.Snapshot
+	if err := json.NewDecoder(f).Decode(&snap); err != nil {
+		return models.Snapshot{}, fmt.Errorf("decoding snapshot %s: %w", path, err)
+	}
+	return snap, nil
+}
+
+// Put encodes snap as indented JSON and stores it under name, replacing any previous snapshot.
+// The file is written next to its final location and renamed over it once complete.
+func (s *FileStore) Put(name string, snap models.Snapshot) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("creating save dir %s: %w", s.dir, err)
+	}
+
+	// CreateTemp already uses 0600 permissions
+	tmp, err := os.CreateTemp(s.dir, "."+name+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp snapshot file in %s: %w", s.dir, err)
+	}
+	// no-op once the rename succeeded
+	defer os.Remove(tmp.Name())
+
+	enc := json.NewEncoder(tmp)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(snap); err != nil {
+		tmp.Close()
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing snapshot file %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing snapshot file %s: %w", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing snapshot file %s: %w", path, err)
+	}
+	return nil
+}
+
+// List returns the names of all stored snapshots, sorted.
+func (s *FileStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading save dir %s: %w", s.dir, err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		// skip directories and leftover temp files from interrupted writes
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete removes the snapshot called name.
+func (s *FileStore) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	} else if err != nil {
+		return fmt.Errorf("removing snapshot %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+)
+
+func TestFileStorePermissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "saves")
+	s := NewFileStore(dir)
+	if err := s.Put("work", models.Snapshot{Name: "work"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]os.FileMode{dir: 0o700, filepath.Join(dir, "work.json"): 0o600} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s has mode %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestFileStorePutFailure(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, dir string)
+		snap  models.Snapshot
+	}{
+		{
+			name: "encoding fails",
+			snap: models.Snapshot{Workspaces: []models.WorkspaceSnapshot{{Root: models.LayoutNode{Percent: math.NaN()}}}},
+		},
+		{
+			name: "rename fails",
+			setup: func(t *testing.T, dir string) {
+				// a non-empty directory cannot be replaced by a file
+				if err := os.MkdirAll(filepath.Join(dir, "work.json", "x"), 0o700); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.setup != nil {
+				tt.setup(t, dir)
+			}
+			s := NewFileStore(dir)
+			if err := s.Put("work", tt.snap); err == nil {
+				t.Fatal("Put succeeded")
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			if slices.ContainsFunc(names, func(n string) bool { return filepath.Ext(n) == ".tmp" }) {
+				t.Errorf("temp file left behind: %q", names)
+			}
+		})
+	}
+}
+
+func TestFileStoreListSkipsTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	s := NewFileStore(dir)
+	if err := s.Put("work", models.Snapshot{Name: "work"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{".work.123.tmp", ".work.clean", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err := s.List()
+	if want := []string{"work"}; err != nil || !slices.Equal(names, want) {
+		t.Errorf("List() = %q, %v, want %q", names, err, want)
+	}
+}
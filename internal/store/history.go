@@ -0,0 +1,319 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+)
+
+// generationLayout formats the timestamp of a kept generation, in local time:
+// "work@2026-10-17T09:30:00". References may use any prefix of it ("work@2026-10-17T09:30").
+// A generation taken in the same second as an existing one gets milliseconds added
+// ("work@2026-10-17T09:30:00.250"), which parsing with this layout accepts as well.
+const generationLayout = "2006-01-02T15:04:05"
+
+// Retention decides which older generations of a snapshot are kept.
+// A generation survives if it is one of the KeepLast newest ones, or if it is the newest
+// generation of its day and that day is within the last KeepDaily days.
+type Retention struct {
+	KeepLast  int
+	KeepDaily int
+}
+
+// DefaultRetention keeps the last 10 generations plus one per day for a week.
+var DefaultRetention = Retention{KeepLast: 10, KeepDaily: 7}
+
+// Generation is an older version of a snapshot kept by HistoryStore.
+type Generation struct {
+	Ref  string    // name to Get it with, e.g. "work@2026-10-17T09:30:00"
+	Time time.Time // when it was taken
+}
+
+// HistoryStore wraps a SnapshotStore and keeps previous generations of every snapshot:
+// putting a snapshot under an existing name first moves the current one aside as
+// "<name>@<timestamp>", then prunes old generations according to the retention policy.
+//
+// Get understands references to older generations: "work@2026-10-17T09:30" picks the
+// newest generation taken at that (possibly partial) timestamp and "work~2" the second
+// to last one ("work~0" is the current snapshot).
+type HistoryStore struct {
+	base      SnapshotStore
+	retention Retention
+	now       func() time.Time
+}
+
+// NewHistoryStore returns a HistoryStore keeping generations in base.
+func NewHistoryStore(base SnapshotStore, retention Retention) *HistoryStore {
+	return &HistoryStore{base: base, retention: retention, now: time.Now}
+}
+
+// Get returns the snapshot or generation ref refers to.
+func (s *HistoryStore) Get(ref string) (models.Snapshot, error) {
+	name, err := s.resolve(ref)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+	return s.base.Get(name)
+}
+
+// Put stores snap under name, keeping the snapshot it replaces as a generation.
+// If name refers to an existing generation, that generation is overwritten in place;
+// references to nothing cannot be used as a new name.
+func (s *HistoryStore) Put(name string, snap models.Snapshot) error {
+	if isRef(name) {
+		resolved, err := s.resolve(name)
+		if errors.Is(err, ErrNotFound) {
+			return refNameError(name)
+		}
+		if err != nil {
+			return err
+		}
+		if isRef(resolved) {
+			return s.base.Put(resolved, snap)
+		}
+		// "work~0" is the current snapshot, which keeps its generation as usual
+		name = resolved
+	}
+
+	if prev, err := s.base.Get(name); err == nil {
+		taken := s.now()
+		if prev.Metadata != nil && !prev.Metadata.CreatedAt.IsZero() {
+			taken = prev.Metadata.CreatedAt
+		}
+		gen, err := s.freeGenerationName(name, taken)
+		if err != nil {
+			return err
+		}
+		if err := s.base.Put(gen, prev); err != nil {
+			return fmt.Errorf("keeping previous generation of %s: %w", name, err)
+		}
+	}
+
+	if err := s.base.Put(name, snap); err != nil {
+		return err
+	}
+	return s.prune(name)
+}
+
+// List returns the names of all current snapshots, without their generations.
+func (s *HistoryStore) List() ([]string, error) {
+	all, err := s.base.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, n := range all {
+		if !isRef(n) {
+			names = append(names, n)
+		}
+	}
+	return names, nil
+}
+
+// Delete removes a single generation if ref refers to one, otherwise the snapshot
+// together with all its generations.
+func (s *HistoryStore) Delete(ref string) error {
+	if isRef(ref) {
+		name, err := s.resolve(ref)
+		if err != nil {
+			return err
+		}
+		return s.base.Delete(name)
+	}
+
+	gens, err := s.Generations(ref)
+	if err != nil {
+		return err
+	}
+	if err := s.base.Delete(ref); err != nil {
+		return err
+	}
+	for _, g := range gens {
+		if err := s.base.Delete(g.Ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rename moves the snapshot src to dst together with all its generations. It fails if
+// dst already exists or reads as a generation reference. If src refers to a generation,
+// only that generation is moved.
+func (s *HistoryStore) Rename(src, dst string) error {
+	if isRef(dst) {
+		return refNameError(dst)
+	}
+	if isRef(src) {
+		if err := Copy(s, src, dst); err != nil {
+			return err
+		}
+		return s.Delete(src)
+	}
+
+	snap, err := s.base.Get(src)
+	if err != nil {
+		return err
+	}
+	if err := ensureAbsent(s, dst); err != nil {
+		return err
+	}
+	gens, err := s.Generations(src)
+	if err != nil {
+		return err
+	}
+
+	// copy everything before deleting anything, so a failure never loses a generation
+	snap.Name = dst
+	if err := s.base.Put(dst, snap); err != nil {
+		return err
+	}
+	for _, g := range gens {
+		gen, err := s.base.Get(g.Ref)
+		if err != nil {
+			return err
+		}
+		gen.Name = dst
+		// keep the exact timestamp, which may carry milliseconds
+		if err := s.base.Put(dst+strings.TrimPrefix(g.Ref, src), gen); err != nil {
+			return fmt.Errorf("moving generation %s: %w", g.Ref, err)
+		}
+	}
+	return s.Delete(src)
+}
+
+// Generations returns the older generations of the snapshot called name, newest first.
+func (s *HistoryStore) Generations(name string) ([]Generation, error) {
+	all, err := s.base.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var gens []Generation
+	prefix := name + "@"
+	for _, n := range all {
+		if !strings.HasPrefix(n, prefix) {
+			continue
+		}
+		t, err := time.ParseInLocation(generationLayout, strings.TrimPrefix(n, prefix), time.Local)
+		if err != nil {
+			continue // not one of ours
+		}
+		gens = append(gens, Generation{Ref: n, Time: t})
+	}
+
+	sort.Slice(gens, func(i, j int) bool { return gens[i].Time.After(gens[j].Time) })
+	return gens, nil
+}
+
+// resolve turns a reference into the name of a stored snapshot.
+func (s *HistoryStore) resolve(ref string) (string, error) {
+	if name, n, ok := strings.Cut(ref, "~"); ok {
+		back, err := strconv.Atoi(n)
+		if err != nil || back < 0 {
+			return "", fmt.Errorf("invalid generation %q, expected <name>~<number>", ref)
+		}
+		if back == 0 {
+			return name, nil
+		}
+		gens, err := s.Generations(name)
+		if err != nil {
+			return "", err
+		}
+		if back > len(gens) {
+			return "", fmt.Errorf("%w: %s has only %d older generations", ErrNotFound, name, len(gens))
+		}
+		return gens[back-1].Ref, nil
+	}
+
+	if name, stamp, ok := strings.Cut(ref, "@"); ok {
+		gens, err := s.Generations(name)
+		if err != nil {
+			return "", err
+		}
+		// newest first, so the first match is the latest generation at that time
+		for _, g := range gens {
+			if strings.HasPrefix(g.Time.Format(generationLayout), stamp) {
+				return g.Ref, nil
+			}
+		}
+		return "", fmt.Errorf("%w: no generation of %s at %s", ErrNotFound, name, stamp)
+	}
+
+	return ref, nil
+}
+
+// prune deletes the generations of name that the retention policy does not keep.
+func (s *HistoryStore) prune(name string) error {
+	gens, err := s.Generations(name)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	cutoff := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).
+		AddDate(0, 0, -s.retention.KeepDaily+1)
+	seenDays := make(map[string]bool)
+
+	for i, g := range gens {
+		keep := i < s.retention.KeepLast
+
+		// gens is newest first, so the first generation seen for a day is its newest
+		day := g.Time.Format("2006-01-02")
+		if !seenDays[day] {
+			seenDays[day] = true
+			if s.retention.KeepDaily > 0 && !g.Time.Before(cutoff) {
+				keep = true
+			}
+		}
+
+		if !keep {
+			if err := s.base.Delete(g.Ref); err != nil {
+				return fmt.Errorf("pruning %s: %w", g.Ref, err)
+			}
+		}
+	}
+	return nil
+}
+
+// generationName is the name an older generation of name taken at t is stored under.
+func generationName(name string, t time.Time) string {
+	return name + "@" + t.In(time.Local).Format(generationLayout)
+}
+
+// freeGenerationName returns the name to keep a generation of name taken at t under,
+// without overwriting an existing generation: if the name to the second is taken,
+// milliseconds are added, moved on by one while that name is taken too.
+func (s *HistoryStore) freeGenerationName(name string, t time.Time) (string, error) {
+	all, err := s.base.List()
+	if err != nil {
+		return "", err
+	}
+	taken := make(map[string]bool, len(all))
+	for _, n := range all {
+		taken[n] = true
+	}
+
+	gen := generationName(name, t)
+	for taken[gen] {
+		gen = name + "@" + t.In(time.Local).Format(generationLayout+".000")
+		t = t.Add(time.Millisecond)
+	}
+	return gen, nil
+}
+
+// refNameError is returned for a new snapshot name that would read as a reference to a
+// generation, so the snapshot could never be listed or loaded under it.
+func refNameError(name string) error {
+	return fmt.Errorf("invalid snapshot name %q: @ and ~ refer to older generations", name)
+}
+
+// isRef reports whether name refers to a generation rather than a current snapshot.
+func isRef(name string) bool {
+	return strings.ContainsAny(name, "@~")
+}
@@ -0,0 +1,261 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+)
+
+// snapshotAt returns a snapshot called name taken at t.
+func snapshotAt(name string, t time.Time) models.Snapshot {
+	return models.Snapshot{Name: name, Metadata: &models.Metadata{CreatedAt: t}}
+}
+
+func TestRenameKeepsGenerations(t *testing.T) {
+	base := NewMemoryStore()
+	s := NewHistoryStore(base, DefaultRetention)
+	first := time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local)
+	s.now = func() time.Time { return first.Add(2 * time.Hour) }
+
+	for i := range 3 {
+		if err := s.Put("work", snapshotAt("work", first.Add(time.Duration(i)*time.Hour))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Rename(s, "work", "home"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+
+	names, err := base.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"home", "home@2026-10-16T09:30:00", "home@2026-10-16T10:30:00"}
+	if !slices.Equal(names, want) {
+		t.Errorf("stored names = %q, want %q", names, want)
+	}
+
+	gen, err := s.Get("home~2")
+	if err != nil {
+		t.Fatalf("Get(home~2): %v", err)
+	}
+	if gen.Name != "home" || !gen.Metadata.CreatedAt.Equal(first) {
+		t.Errorf("home~2 = %s taken at %v, want home taken at %v", gen.Name, gen.Metadata.CreatedAt, first)
+	}
+}
+
+func TestRenameGeneration(t *testing.T) {
+	base := NewMemoryStore()
+	s := NewHistoryStore(base, DefaultRetention)
+	first := time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local)
+	s.now = func() time.Time { return first.Add(time.Hour) }
+
+	for i := range 2 {
+		if err := s.Put("work", snapshotAt("work", first.Add(time.Duration(i)*time.Hour))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Rename(s, "work~1", "old"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+
+	names, err := base.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"old", "work"}; !slices.Equal(names, want) {
+		t.Errorf("stored names = %q, want %q", names, want)
+	}
+}
+
+func TestRenameExistingTarget(t *testing.T) {
+	s := NewHistoryStore(NewMemoryStore(), DefaultRetention)
+	for _, name := range []string{"work", "home"} {
+		if err := s.Put(name, models.Snapshot{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Rename(s, "work", "home"); err == nil {
+		t.Fatal("Rename onto an existing snapshot succeeded")
+	}
+	if _, err := s.Get("work"); err != nil {
+		t.Errorf("source lost after failed rename: %v", err)
+	}
+}
+
+// historyAt returns a HistoryStore over a memory store holding the given snapshots, with
+// its clock stopped at now.
+func historyAt(t *testing.T, now time.Time, retention Retention, names ...string) (*HistoryStore, *MemoryStore) {
+	t.Helper()
+	base := NewMemoryStore()
+	for _, name := range names {
+		if err := base.Put(name, models.Snapshot{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewHistoryStore(base, retention)
+	s.now = func() time.Time { return now }
+	return s, base
+}
+
+func TestResolve(t *testing.T) {
+	s, _ := historyAt(t, time.Now(), DefaultRetention,
+		"work", "work@2026-10-16T09:30:00", "work@2026-10-16T10:30:00", "work@2026-10-17T08:00:00", "other")
+
+	tests := []struct {
+		ref      string
+		want     string
+		notFound bool // error wraps ErrNotFound
+		invalid  bool // any other error
+	}{
+		{ref: "work", want: "work"},
+		{ref: "work~0", want: "work"},
+		{ref: "work~1", want: "work@2026-10-17T08:00:00"},
+		{ref: "work~3", want: "work@2026-10-16T09:30:00"},
+		{ref: "work~4", notFound: true},
+		{ref: "other~1", notFound: true},
+		{ref: "work~x", invalid: true},
+		{ref: "work~-1", invalid: true},
+		{ref: "work@2026-10-16", want: "work@2026-10-16T10:30:00"}, // newest of the day
+		{ref: "work@2026-10-16T09", want: "work@2026-10-16T09:30:00"},
+		{ref: "work@2026-10-17T08:00:00", want: "work@2026-10-17T08:00:00"},
+		{ref: "work@2026-10-18", notFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := s.resolve(tt.ref)
+			switch {
+			case tt.notFound:
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("resolve(%q) = %q, %v, want ErrNotFound", tt.ref, got, err)
+				}
+			case tt.invalid:
+				if err == nil || errors.Is(err, ErrNotFound) {
+					t.Errorf("resolve(%q) = %q, %v, want an invalid reference error", tt.ref, got, err)
+				}
+			case err != nil || got != tt.want:
+				t.Errorf("resolve(%q) = %q, %v, want %q", tt.ref, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name      string
+		retention Retention
+		gens      []string
+		want      []string
+	}{
+		{
+			name:      "last only",
+			retention: Retention{KeepLast: 2},
+			gens:      []string{"2026-10-17T11:00:00", "2026-10-17T10:00:00", "2026-10-16T09:00:00", "2026-10-10T09:00:00"},
+			want:      []string{"2026-10-17T10:00:00", "2026-10-17T11:00:00"},
+		},
+		{
+			name:      "daily only",
+			retention: Retention{KeepDaily: 2},
+			// the cutoff is the start of yesterday: today and yesterday keep their newest
+			gens: []string{"2026-10-17T11:00:00", "2026-10-17T10:00:00", "2026-10-16T23:00:00", "2026-10-16T00:00:00", "2026-10-15T23:59:59"},
+			want: []string{"2026-10-16T23:00:00", "2026-10-17T11:00:00"},
+		},
+		{
+			name:      "last and daily",
+			retention: Retention{KeepLast: 1, KeepDaily: 3},
+			gens:      []string{"2026-10-17T11:00:00", "2026-10-17T10:00:00", "2026-10-15T09:00:00", "2026-10-14T23:00:00"},
+			want:      []string{"2026-10-15T09:00:00", "2026-10-17T11:00:00"},
+		},
+		{
+			name:      "nothing kept",
+			retention: Retention{},
+			gens:      []string{"2026-10-17T11:00:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := []string{"work", "other@2026-10-01T00:00:00"}
+			for _, g := range tt.gens {
+				names = append(names, "work@"+g)
+			}
+			s, base := historyAt(t, now, tt.retention, names...)
+
+			if err := s.prune("work"); err != nil {
+				t.Fatalf("prune: %v", err)
+			}
+
+			want := []string{"other@2026-10-01T00:00:00", "work"}
+			for _, g := range tt.want {
+				want = append(want, "work@"+g)
+			}
+			slices.Sort(want)
+			if got, _ := base.List(); !slices.Equal(got, want) {
+				t.Errorf("kept %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestPutSameSecond(t *testing.T) {
+	taken := time.Date(2026, 10, 16, 9, 30, 0, 100*int(time.Millisecond), time.Local)
+	s, base := historyAt(t, taken.Add(time.Hour), DefaultRetention)
+
+	// the same snapshot saved back again and again, e.g. after migrations
+	for range 4 {
+		if err := s.Put("work", snapshotAt("work", taken)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, _ := base.List()
+	want := []string{"work", "work@2026-10-16T09:30:00", "work@2026-10-16T09:30:00.100", "work@2026-10-16T09:30:00.101"}
+	if !slices.Equal(names, want) {
+		t.Errorf("stored names = %q, want %q", names, want)
+	}
+	gens, err := s.Generations("work")
+	if err != nil || len(gens) != 3 {
+		t.Fatalf("Generations() = %v, %v, want 3", gens, err)
+	}
+}
+
+func TestPutReference(t *testing.T) {
+	taken := time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local)
+	s, base := historyAt(t, taken.Add(time.Hour), DefaultRetention)
+	if err := s.Put("work", snapshotAt("work", taken)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the current snapshot: the old one is kept as a generation
+	if err := s.Put("work~0", snapshotAt("work", taken.Add(time.Minute))); err != nil {
+		t.Fatalf("Put(work~0): %v", err)
+	}
+	// an existing generation is overwritten in place
+	if err := s.Put("work~1", models.Snapshot{Name: "work"}); err != nil {
+		t.Fatalf("Put(work~1): %v", err)
+	}
+	names, _ := base.List()
+	if want := []string{"work", "work@2026-10-16T09:30:00"}; !slices.Equal(names, want) {
+		t.Errorf("stored names = %q, want %q", names, want)
+	}
+
+	for _, bad := range []string{"work~2", "new~1", "new@2026", "work@2030"} {
+		if err := s.Put(bad, models.Snapshot{}); err == nil {
+			t.Errorf("Put(%q) succeeded", bad)
+		}
+	}
+	for _, bad := range []string{"a~1", "a@b", "work~5"} {
+		if err := Rename(s, "work", bad); err == nil {
+			t.Errorf("Rename(work, %q) succeeded", bad)
+		}
+		if err := Copy(s, "work", bad); err == nil {
+			t.Errorf("Copy(work, %q) succeeded", bad)
+		}
+	}
+	if names2, _ := base.List(); !slices.Equal(names2, names) {
+		t.Errorf("stored names after rejected writes = %q, want %q", names2, names)
+	}
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SessionMarker records that the session which wrote a snapshot ended cleanly.
+// It is a dot file next to the snapshot in a FileStore directory, so it never shows up in List.
+// The autosave daemon clears it when it starts and sets it when i3 exits, so a missing
+// marker next to an autosave means the previous session crashed.
+type SessionMarker struct {
+	path string
+}
+
+// NewSessionMarker returns the marker for the snapshot called name in dir.
+func NewSessionMarker(dir, name string) (*SessionMarker, error) {
+	if err := validName(name); err != nil {
+		return nil, err
+	}
+	return &SessionMarker{path: filepath.Join(dir, "."+name+".clean")}, nil
+}
+
+// MarkClean records that the session ended cleanly.
+func (m *SessionMarker) MarkClean() error {
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o700); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(m.path), err)
+	}
+	stamp := time.Now().Format(time.RFC3339) + "\n"
+	if err := os.WriteFile(m.path, []byte(stamp), 0o600); err != nil {
+		return fmt.Errorf("writing session marker: %w", err)
+	}
+	return nil
+}
+
+// Clear removes the marker, meaning a session is running.
+func (m *SessionMarker) Clear() error {
+	if err := os.Remove(m.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing session marker: %w", err)
+	}
+	return nil
+}
+
+// IsClean reports whether the last session ended cleanly.
+func (m *SessionMarker) IsClean() (bool, error) {
+	_, err := os.Stat(m.path)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return false, fmt.Errorf("reading session marker: %w", err)
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionMarker(t *testing.T) {
+	// the directory does not exist yet, as before the first autosave
+	m, err := NewSessionMarker(filepath.Join(t.TempDir(), "saves"), "autosave")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name  string
+		do    func() error
+		clean bool
+	}{
+		{"no marker yet", nil, false},
+		{"session ended", m.MarkClean, true},
+		{"ended twice", m.MarkClean, true},
+		{"next session running", m.Clear, false},
+		{"cleared twice", m.Clear, false},
+		{"next session ended", m.MarkClean, true},
+	}
+	for _, step := range steps {
+		if step.do != nil {
+			if err := step.do(); err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+		}
+		clean, err := m.IsClean()
+		if err != nil || clean != step.clean {
+			t.Errorf("%s: IsClean() = %v, %v, want %v", step.name, clean, err, step.clean)
+		}
+	}
+}
+
+func TestSessionMarkerInvalidName(t *testing.T) {
+	if _, err := NewSessionMarker(t.TempDir(), "../autosave"); err == nil {
+		t.Error("NewSessionMarker accepted a name outside the directory")
+	}
+}
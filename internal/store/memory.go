@@ -0,0 +1,80 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+)
+
+// MemoryStore keeps snapshots in memory, for tests and dry runs.
+// Snapshots are stored as JSON so callers never share slices or maps with the store,
+// just like with FileStore.
+type MemoryStore struct {
+	mu    sync.Mutex
+	snaps map[string][]byte
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{snaps: make(map[string][]byte)}
+}
+
+// Get returns the snapshot called name.
+func (s *MemoryStore) Get(name string) (models.Snapshot, error) {
+	s.mu.Lock()
+	data, ok := s.snaps[name]
+	s.mu.Unlock()
+	if !ok {
+		return models.Snapshot{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	var snap models.Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return models.Snapshot{}, fmt.Errorf("decoding snapshot %s: %w", name, err)
+	}
+	return snap, nil
+}
+
+// Put stores snap under name.
+func (s *MemoryStore) Put(name string, snap models.Snapshot) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snaps[name] = data
+	return nil
+}
+
+// List returns the names of all stored snapshots, sorted.
+func (s *MemoryStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.snaps))
+	for name := range s.snaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete removes the snapshot called name.
+func (s *MemoryStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.snaps[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	delete(s.snaps, name)
+	return nil
+}
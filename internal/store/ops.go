@@ -0,0 +1,49 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Copy stores a copy of snapshot src under dst. It fails if dst already exists.
+func Copy(s SnapshotStore, src, dst string) error {
+	snap, err := s.Get(src)
+	if err != nil {
+		return err
+	}
+	if err := ensureAbsent(s, dst); err != nil {
+		return err
+	}
+
+	snap.Name = dst
+	return s.Put(dst, snap)
+}
+
+// renamer is implemented by stores that keep more than one stored snapshot per name
+// and have to move them together, like HistoryStore.
+type renamer interface {
+	Rename(src, dst string) error
+}
+
+// Rename moves snapshot src to dst. It fails if dst already exists.
+func Rename(s SnapshotStore, src, dst string) error {
+	if r, ok := s.(renamer); ok {
+		return r.Rename(src, dst)
+	}
+	if err := Copy(s, src, dst); err != nil {
+		return err
+	}
+	return s.Delete(src)
+}
+
+// ensureAbsent returns an error unless no snapshot called name exists.
+func ensureAbsent(s SnapshotStore, name string) error {
+	_, err := s.Get(name)
+	if err == nil {
+		return fmt.Errorf("snapshot %s already exists", name)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
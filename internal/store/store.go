@@ -0,0 +1,39 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+)
+
+// ErrNotFound is returned when a snapshot does not exist in the store.
+var ErrNotFound = errors.New("snapshot not found")
+
+// SnapshotStore is where snapshots are kept, by name.
+// Implementations return snapshots exactly as stored: migrating older schema versions
+// is up to the caller.
+type SnapshotStore interface {
+	// Get returns the snapshot called name, or an error wrapping ErrNotFound.
+	Get(name string) (models.Snapshot, error)
+	// Put stores snap under name, replacing any previous snapshot with that name.
+	Put(name string, snap models.Snapshot) error
+	// List returns the names of all stored snapshots, sorted.
+	List() ([]string, error)
+	// Delete removes the snapshot called name, or returns an error wrapping ErrNotFound.
+	Delete(name string) error
+}
+
+var (
+	_ SnapshotStore = (*FileStore)(nil)
+	_ SnapshotStore = (*MemoryStore)(nil)
+)
+
+// validName rejects names that cannot be used as a file name inside a store directory.
+func validName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return fmt.Errorf("invalid snapshot name %q", name)
+	}
+	return nil
+}
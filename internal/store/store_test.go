@@ -0,0 +1,83 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/a9sk/i3-snapshot/internal/models"
+)
+
+// stores returns a fresh instance of every SnapshotStore implementation.
+func stores(t *testing.T) map[string]SnapshotStore {
+	return map[string]SnapshotStore{
+		"memory": NewMemoryStore(),
+		"file":   NewFileStore(t.TempDir()),
+	}
+}
+
+func TestStore(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			names, err := s.List()
+			if err != nil || len(names) != 0 {
+				t.Fatalf("List() on an empty store = %q, %v", names, err)
+			}
+			if _, err := s.Get("work"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Get(work) on an empty store = %v, want ErrNotFound", err)
+			}
+
+			work := models.Snapshot{
+				SchemaVersion: models.SchemaVersion,
+				Name:          "work",
+				Workspaces:    []models.WorkspaceSnapshot{{Name: "1", Windows: []models.WindowRef{{Class: "kitty"}}}},
+			}
+			for _, snap := range []models.Snapshot{work, {Name: "home"}} {
+				if err := s.Put(snap.Name, snap); err != nil {
+					t.Fatalf("Put(%s): %v", snap.Name, err)
+				}
+			}
+
+			got, err := s.Get("work")
+			if err != nil {
+				t.Fatalf("Get(work): %v", err)
+			}
+			if got.Name != "work" || len(got.Workspaces) != 1 || got.Workspaces[0].Windows[0].Class != "kitty" {
+				t.Errorf("Get(work) = %+v, want %+v", got, work)
+			}
+
+			// the store must not share memory with its callers
+			got.Workspaces[0].Name = "changed"
+			if again, _ := s.Get("work"); again.Workspaces[0].Name != "1" {
+				t.Error("changing a returned snapshot changed the stored one")
+			}
+
+			names, err = s.List()
+			if want := []string{"home", "work"}; err != nil || !slices.Equal(names, want) {
+				t.Errorf("List() = %q, %v, want %q", names, err, want)
+			}
+
+			if err := s.Delete("work"); err != nil {
+				t.Fatalf("Delete(work): %v", err)
+			}
+			if _, err := s.Get("work"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get(work) after Delete = %v, want ErrNotFound", err)
+			}
+			if err := s.Delete("work"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second Delete(work) = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestStoreInvalidName(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, bad := range []string{"", ".", "..", "a/b"} {
+				if err := s.Put(bad, models.Snapshot{}); err == nil {
+					t.Errorf("Put(%q) succeeded", bad)
+				}
+			}
+		})
+	}
+}